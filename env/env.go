@@ -1,6 +1,53 @@
 package env
 
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
 var (
+	// Names of the data files inside DataDir, for the JSON and SQLite stores.
 	TodoFileName = "weekt-cli-todos.json"
+	TodoDBName   = "weekt-cli-todos.db"
+
+	// DataFile replaces the data file in DataDir when set.
+	// Set with WEEKTCLI_FILE or the --file flag.
+	DataFile = os.Getenv("WEEKTCLI_FILE")
 	AppName      = "WEEKT-CLI"
-)
\ No newline at end of file
+
+	// RolloverMode decides what happens to unfinished tasks from past days:
+	// "auto", "prompt" or "off". Overridable with WEEKTCLI_ROLLOVER.
+	RolloverMode = getenv("WEEKTCLI_ROLLOVER", "auto")
+
+	// StoreKind picks the storage backend, "json" or "sqlite".
+	// Overridable with WEEKTCLI_STORE or the --store flag.
+	StoreKind = getenv("WEEKTCLI_STORE", "json")
+)
+
+func getenv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// DataDir is where the task data lives: $XDG_DATA_HOME/weektcli, which
+// defaults to ~/.local/share/weektcli (%LOCALAPPDATA%\weektcli on Windows).
+func DataDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "weektcli"), nil
+	}
+	if runtime.GOOS == "windows" {
+		dir, err := os.UserCacheDir() // %LOCALAPPDATA%
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "weektcli"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "weektcli"), nil
+}
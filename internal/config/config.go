@@ -0,0 +1,249 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"weektcli/internal/todo"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Config holds the user settings from config.toml. Settings missing from
+// the file keep their defaults.
+type Config struct {
+	// DataFile replaces the data file in the data directory.
+	DataFile string `toml:"data_file"`
+	// Store is the storage backend, "json" or "sqlite".
+	Store string `toml:"store"`
+	// Rollover is "auto", "prompt" or "off".
+	Rollover string `toml:"rollover"`
+	// TrashDays is how long deleted tasks stay in the trash, 0 keeps them
+	// until the trash is emptied.
+	TrashDays int `toml:"trash_days"`
+	// ArchiveWeeks is how many weeks done tasks stay in the list before
+	// they move to the archive, 0 keeps them in the list.
+	ArchiveWeeks int `toml:"archive_weeks"`
+
+	// WeekStart is the first day of the week in the grid, e.g. "monday".
+	WeekStart string `toml:"week_start"`
+	// DefaultView is where the cursor starts: "today", "week" or "someday".
+	DefaultView string `toml:"default_view"`
+	// Theme is "auto", a bundled theme or a file in ThemesDir.
+	Theme string `toml:"theme"`
+
+	ColumnWidth  int `toml:"column_width"`
+	ColumnHeight int `toml:"column_height"`
+
+	// Go time layouts for the day column headers and for full dates.
+	DayFormat  string `toml:"day_format"`
+	DateFormat string `toml:"date_format"`
+
+	// Keys remaps TUI key bindings: [keys.<mode>] tables of
+	// <action> = ["key", ...]. See tui.KeyMap.Remap.
+	Keys map[string]map[string][]string `toml:"keys,omitempty"`
+}
+
+// Default returns the settings used when there is no config file.
+func Default() Config {
+	return Config{
+		Store:        "json",
+		Rollover:     "auto",
+		TrashDays:    30,
+		ArchiveWeeks: 0,
+		WeekStart:    "monday",
+		DefaultView:  "today",
+		Theme:        "auto",
+		ColumnWidth:  42,
+		ColumnHeight: 19,
+		DayFormat:    "Monday, Jan 02",
+		DateFormat:   "Monday, Jan 02, 2006",
+	}
+}
+
+// Views lists the values DefaultView accepts.
+var Views = []string{"today", "week", "someday"}
+
+// Path is the config file: $XDG_CONFIG_HOME/weektcli/config.toml, or the
+// platform's config directory when that is unset. WEEKTCLI_CONFIG
+// overrides it.
+func Path() (string, error) {
+	if path := os.Getenv("WEEKTCLI_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		var err error
+		if dir, err = os.UserConfigDir(); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(dir, "weektcli", "config.toml"), nil
+}
+
+// ThemesDir holds user theme files (<name>.toml), next to the config file.
+func ThemesDir() (string, error) {
+	path, err := Path()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "themes"), nil
+}
+
+// Load reads the config file at path on top of the defaults. A missing
+// file is not an error, unknown keys and invalid values are.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	meta, err := toml.DecodeFile(path, &cfg)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return cfg, fmt.Errorf("%s: unknown setting %q, expected one of %s", path, undecoded[0].String(), strings.Join(Keys(), ", "))
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Save writes the config to path, creating its directory.
+func (c Config) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := toml.NewEncoder(f).Encode(c); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Validate reports the first setting that has an unusable value.
+func (c Config) Validate() error {
+	if _, err := todo.ParseStoreKind(c.Store); err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+	if _, err := todo.ParseRolloverMode(c.Rollover); err != nil {
+		return fmt.Errorf("rollover: %w", err)
+	}
+	if c.TrashDays < 0 {
+		return fmt.Errorf("trash_days: %d must not be negative, use 0 to keep deleted tasks", c.TrashDays)
+	}
+	if c.ArchiveWeeks < 0 {
+		return fmt.Errorf("archive_weeks: %d must not be negative, use 0 to keep done tasks", c.ArchiveWeeks)
+	}
+	if _, err := todo.ParseWeekday(c.WeekStart); err != nil {
+		return fmt.Errorf("week_start: %w", err)
+	}
+	if !contains(Views, c.DefaultView) {
+		return fmt.Errorf("default_view: unknown view %q, expected one of %s", c.DefaultView, strings.Join(Views, ", "))
+	}
+	if strings.TrimSpace(c.Theme) == "" {
+		return fmt.Errorf("theme: must not be empty, use \"auto\" to follow the terminal")
+	}
+	if c.ColumnWidth < 20 || c.ColumnWidth > 120 {
+		return fmt.Errorf("column_width: %d is out of range 20-120", c.ColumnWidth)
+	}
+	if c.ColumnHeight < 8 || c.ColumnHeight > 60 {
+		return fmt.Errorf("column_height: %d is out of range 8-60", c.ColumnHeight)
+	}
+	for key, layout := range map[string]string{"day_format": c.DayFormat, "date_format": c.DateFormat} {
+		if layout == "" || time.Date(1999, 11, 23, 0, 0, 0, 0, time.UTC).Format(layout) == layout {
+			return fmt.Errorf("%s: %q is not a Go time layout, e.g. \"Monday, Jan 02\"", key, layout)
+		}
+	}
+	return nil
+}
+
+// WeekStartDay is WeekStart as a time.Weekday.
+func (c Config) WeekStartDay() time.Weekday {
+	wd, _ := todo.ParseWeekday(c.WeekStart)
+	return wd
+}
+
+// Keys lists the setting names in file order. The [keys] tables are not
+// settings of their own and are left out.
+func Keys() []string {
+	var keys []string
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Type.Kind() == reflect.Map {
+			continue
+		}
+		keys = append(keys, tomlName(t.Field(i)))
+	}
+	return keys
+}
+
+func tomlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("toml"), ",")
+	return name
+}
+
+// Get returns the value of a setting as text.
+func (c Config) Get(key string) (string, error) {
+	field, err := c.field(key)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(field.Interface()), nil
+}
+
+// Set changes a setting from its text form. It does not validate the
+// result, call Validate for that.
+func (c *Config) Set(key, value string) error {
+	field, err := c.field(key)
+	if err != nil {
+		return err
+	}
+	switch field.Kind() {
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a number", key, value)
+		}
+		field.SetInt(int64(n))
+	default:
+		field.SetString(value)
+	}
+	return nil
+}
+
+func (c *Config) field(key string) (reflect.Value, error) {
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if tomlName(v.Type().Field(i)) != key {
+			continue
+		}
+		if v.Field(i).Kind() == reflect.Map {
+			return reflect.Value{}, fmt.Errorf("%s: edit the [%s] tables with: weektcli config edit", key, key)
+		}
+		return v.Field(i), nil
+	}
+	keys := Keys()
+	sort.Strings(keys)
+	return reflect.Value{}, fmt.Errorf("unknown setting %q, expected one of %s", key, strings.Join(keys, ", "))
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,247 @@
+package todo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ArchiveName is the file the archive of the data file filename is kept
+// in, e.g. weekt-cli-todos-archive.json.
+func ArchiveName(filename string) string {
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + "-archive" + ext
+}
+
+// OpenArchive opens the archive store that belongs to s. It uses the same
+// backend as s.
+func OpenArchive(s Store) (Store, error) {
+	kind := "json"
+	if _, ok := s.(*SQLiteStore); ok {
+		kind = "sqlite"
+	}
+	return OpenStore(kind, ArchiveName(s.Path()))
+}
+
+// Completion is a checked-off task, or one checked-off occurrence of a
+// recurring task, as the logbook shows it.
+type Completion struct {
+	ID    uuid.UUID `json:"id"`
+	Task  string    `json:"task"`
+	Notes string    `json:"notes,omitempty"`
+	// Day is when it was done. Tasks checked off before weektcli recorded
+	// that use the day they were planned for.
+	Day time.Time `json:"day"`
+}
+
+// completionDay is the day a done one-time task was finished, zero when
+// that is unknown.
+func (it Item) completionDay() time.Time {
+	if it.CompletedAt != nil {
+		return midnight(*it.CompletedAt)
+	}
+	if it.Date.IsZero() {
+		return time.Time{}
+	}
+	return midnight(it.Date)
+}
+
+// occurrenceID is the ID an archived occurrence of series gets, the same
+// every time so archiving a day twice stores it once.
+func occurrenceID(series uuid.UUID, day string) uuid.UUID {
+	return uuid.NewSHA1(series, []byte(day))
+}
+
+// Completions lists everything checked off in l, newest first. Trashed
+// items are left out.
+func (l List) Completions() []Completion {
+	var out []Completion
+	for _, it := range l {
+		if it.Trashed() {
+			continue
+		}
+		if it.RecurrenceRule == nil {
+			if day := it.completionDay(); it.Done && !day.IsZero() {
+				out = append(out, Completion{ID: it.ID, Task: it.Task, Notes: it.Notes, Day: day})
+			}
+			continue
+		}
+		for _, key := range it.RecurrenceRule.DoneList {
+			day, err := time.ParseInLocation(DateLayout, key, time.Local)
+			if err != nil {
+				continue
+			}
+			occ := it.occurrence(day, day)
+			out = append(out, Completion{ID: occurrenceID(it.ID, key), Task: occ.Task, Notes: occ.Notes, Day: day})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Day.After(out[j].Day) })
+	return out
+}
+
+// Logbook combines the completions of the archive and of the list from
+// since on, newest first. Something in both, like an archived task brought
+// back by undo, is listed once.
+func Logbook(archive, l List, since time.Time) []Completion {
+	seen := map[uuid.UUID]bool{}
+	var out []Completion
+	for _, c := range append(l.Completions(), archive.Completions()...) {
+		if seen[c.ID] || c.Day.Before(midnight(since)) {
+			continue
+		}
+		seen[c.ID] = true
+		out = append(out, c)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Day.After(out[j].Day) })
+	return out
+}
+
+// Archive takes what was completed before cutoff out of l and returns it
+// for the archive store: done one-time tasks, and the checked-off
+// occurrences of recurring tasks as one-time tasks of their own.
+func (l *List) Archive(cutoff time.Time) List {
+	cutoff = midnight(cutoff)
+
+	var archived List
+	kept := (*l)[:0]
+	for _, it := range *l {
+		switch {
+		case it.Trashed():
+		case it.RecurrenceRule == nil:
+			if day := it.completionDay(); it.Done && !day.IsZero() && day.Before(cutoff) {
+				archived = append(archived, it)
+				continue
+			}
+		default:
+			archived = append(archived, it.archiveOccurrences(cutoff)...)
+		}
+		kept = append(kept, it)
+	}
+	*l = kept
+	return archived
+}
+
+// archiveOccurrences moves the DoneList entries before cutoff out of the
+// rule and records them as archived, see ArchivedRuns. The latest
+// completion of a rule that repeats after completion stays, it decides
+// when the task is due next.
+func (it *Item) archiveOccurrences(cutoff time.Time) List {
+	rule := it.RecurrenceRule
+	key := cutoff.Format(DateLayout)
+
+	latest := ""
+	if rule.AfterCompletion {
+		for _, d := range rule.DoneList {
+			latest = max(latest, d)
+		}
+	}
+
+	var kept, days []string
+	var out List
+	for _, d := range rule.DoneList {
+		day, err := time.ParseInLocation(DateLayout, d, time.Local)
+		if err != nil || d >= key || d == latest {
+			kept = append(kept, d)
+			continue
+		}
+		occ := it.occurrence(day, day)
+		out = append(out, Item{
+			ID:          occurrenceID(it.ID, d),
+			Task:        occ.Task,
+			Notes:       occ.Notes,
+			Done:        true,
+			CompletedAt: &day,
+			Date:        day,
+		})
+		days = append(days, d)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+
+	rule.DoneList = kept
+	if rule.AfterCompletion {
+		rule.Archived += uint16(len(out))
+	} else {
+		it.recordArchived(days)
+	}
+	return out
+}
+
+// recordArchived adds days to the archived runs of the rule. It walks the
+// schedule from the first archived day on, so days archived earlier and
+// days in between the series skips join up with them into one run.
+func (it *Item) recordArchived(days []string) {
+	rule := it.RecurrenceRule
+	archived := map[string]bool{}
+	for _, d := range days {
+		archived[d] = true
+	}
+
+	first, last := slices.Min(days), slices.Max(days)
+	for _, run := range rule.ArchivedRuns {
+		from, to, _ := strings.Cut(run, "/")
+		first, last = min(first, from), max(last, to)
+	}
+	d, err := time.ParseInLocation(DateLayout, first, time.Local)
+	if err != nil {
+		return
+	}
+
+	var runs []string
+	from, to := "", ""
+	for ; d.Format(DateLayout) <= last; d = d.AddDate(0, 0, 1) {
+		key := d.Format(DateLayout)
+		switch {
+		case archived[key] || rule.archivedOn(key):
+			if from == "" {
+				from = key
+			}
+			to = key
+		case it.scheduledOn(d) && from != "":
+			// Missed, the run ends before it
+			runs = append(runs, from+"/"+to)
+			from = ""
+		}
+	}
+	if from != "" {
+		runs = append(runs, from+"/"+to)
+	}
+	rule.ArchivedRuns = runs
+}
+
+// archivedOn reports whether the occurrence on day (YYYY-MM-DD) was moved
+// to the archive.
+func (r *RecurrenceRule) archivedOn(day string) bool {
+	if day < r.ArchivedBefore {
+		return true
+	}
+	for _, run := range r.ArchivedRuns {
+		if from, to, _ := strings.Cut(run, "/"); day >= from && day <= to {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadArchive reads the archive that belongs to s, see OpenArchive. It is
+// empty until something was archived.
+func LoadArchive(s Store) (List, error) {
+	archive, err := OpenArchive(s)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	l, err := archive.Load()
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, ErrRestoredBackup) {
+		err = nil
+	}
+	return l, err
+}
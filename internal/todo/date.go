@@ -0,0 +1,115 @@
+package todo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateHelp lists the date expressions ParseDate understands, for help
+// texts.
+const DateHelp = `YYYY-MM-DD, today, tomorrow, yesterday, a weekday like "fri", a day of the month like "1st", "next monday", "in 3 days", "2 weeks ago", "end of week", "end of month" or someday`
+
+// ParseDate reads a date expression relative to now. Weekdays are the next
+// one from today on, "next <weekday>" is that day in the week after this
+// one, where weeks start on first. The result is a day at midnight, or
+// someday for "someday".
+func ParseDate(s string, now time.Time, first time.Weekday) (day time.Time, someday bool, err error) {
+	expr := strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	today := midnight(now)
+
+	switch expr {
+	case "":
+		return time.Time{}, false, fmt.Errorf("no date given, try \"fri\", \"in 3 days\" or YYYY-MM-DD")
+	case "someday":
+		return time.Time{}, true, nil
+	case "today", "now":
+		return today, false, nil
+	case "tomorrow", "tmr":
+		return today.AddDate(0, 0, 1), false, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), false, nil
+	case "end of week", "eow":
+		return StartOfWeek(today, first).AddDate(0, 0, 6), false, nil
+	case "next week":
+		return StartOfWeek(today, first).AddDate(0, 0, 7), false, nil
+	case "last week":
+		return StartOfWeek(today, first).AddDate(0, 0, -7), false, nil
+	case "end of month", "eom":
+		return time.Date(today.Year(), today.Month()+1, 0, 0, 0, 0, 0, time.Local), false, nil
+	case "next month":
+		return time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, time.Local), false, nil
+	}
+
+	if d, err := time.ParseInLocation(DateLayout, expr, time.Local); err == nil {
+		return d, false, nil
+	}
+	if wd, err := ParseWeekday(expr); err == nil {
+		return today.AddDate(0, 0, (int(wd)-int(today.Weekday())+7)%7), false, nil
+	}
+	if n, ok := ordinal(expr); ok && n >= 1 && n <= 31 {
+		// the next month that has the day, from this one on
+		for d := today.AddDate(0, 0, 1-today.Day()); ; d = d.AddDate(0, 1, 0) {
+			if day := d.AddDate(0, 0, n-1); day.Month() == d.Month() && !day.Before(today) {
+				return day, false, nil
+			}
+		}
+	}
+	if rest, ok := strings.CutPrefix(expr, "next "); ok {
+		if wd, err := ParseWeekday(rest); err == nil {
+			next := StartOfWeek(today, first).AddDate(0, 0, 7)
+			return next.AddDate(0, 0, (int(wd)-int(first)+7)%7), false, nil
+		}
+	}
+	if rest, ok := strings.CutPrefix(expr, "in "); ok {
+		if d, ok := addPeriod(today, rest, 1); ok {
+			return d, false, nil
+		}
+	}
+	if rest, ok := strings.CutSuffix(expr, " ago"); ok {
+		if d, ok := addPeriod(today, rest, -1); ok {
+			return d, false, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("unknown date %q, try \"fri\", \"in 3 days\" or YYYY-MM-DD", s)
+}
+
+// ordinal reads "1st", "2nd", "3rd", "4th" and so on.
+func ordinal(s string) (int, bool) {
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		if digits, ok := strings.CutSuffix(s, suffix); ok {
+			n, err := strconv.Atoi(digits)
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+// addPeriod adds a period like "3 days", "a week" or "2 months" to day,
+// or subtracts it for a negative sign.
+func addPeriod(day time.Time, period string, sign int) (time.Time, bool) {
+	count, unit, ok := strings.Cut(period, " ")
+	if !ok {
+		return time.Time{}, false
+	}
+	n, err := strconv.Atoi(count)
+	if count == "a" || count == "an" {
+		n, err = 1, nil
+	}
+	if err != nil || n < 0 {
+		return time.Time{}, false
+	}
+	n *= sign
+	switch strings.TrimSuffix(unit, "s") {
+	case "day":
+		return day.AddDate(0, 0, n), true
+	case "week":
+		return day.AddDate(0, 0, 7*n), true
+	case "month":
+		return day.AddDate(0, n, 0), true
+	case "year":
+		return day.AddDate(n, 0, 0), true
+	}
+	return time.Time{}, false
+}
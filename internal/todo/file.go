@@ -0,0 +1,79 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrRestoredBackup is returned by Load when the data file was unreadable
+// and the list was loaded from the backup instead.
+var ErrRestoredBackup = errors.New("data file is corrupt, loaded the backup instead")
+
+// BackupName is where Save keeps the previous version of a data file.
+func BackupName(filename string) string {
+	return filename + ".bak"
+}
+
+// writeFileAtomic replaces filename with data without ever leaving a
+// half-written file behind: the data goes to a temp file in the same
+// directory, is synced to disk and then renamed over the original.
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(filename)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op once the rename went through
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, filename); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// syncDir makes a rename in dir durable. Not every platform can open a
+// directory for syncing, so failures are ignored.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// backup copies the current data file to its backup, but only when it
+// still parses: a corrupt file must never replace a good backup.
+func backup(filename string) error {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var l List
+	if err := l.decode(data); err != nil {
+		return nil
+	}
+	if err := writeFileAtomic(BackupName(filename), data, 0644); err != nil {
+		return fmt.Errorf("backup %s: %w", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package todo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/google/uuid"
+)
+
+// HistoryLimit is how many commands History keeps to undo.
+const HistoryLimit = 100
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// HistoryName is the file the undo history of the data file filename is
+// kept in.
+func HistoryName(filename string) string {
+	return filename + ".history"
+}
+
+// Change is one item before and after a command. Before is nil for items
+// the command added, After for items it deleted.
+type Change struct {
+	ID     uuid.UUID `json:"id"`
+	Before *Item     `json:"before,omitempty"`
+	After  *Item     `json:"after,omitempty"`
+}
+
+// Command is one saved mutation of the list, reversible by putting back
+// the Before side of its changes.
+type Command struct {
+	Changes []Change `json:"changes"`
+}
+
+// newCommand records what changed between two versions of a list. The
+// items share recurrence rules with the lists, so the command has to be
+// written out before either list changes again.
+func newCommand(before, after List) Command {
+	changed, removed := Diff(before, after)
+	var c Command
+	for _, id := range changed {
+		c.Changes = append(c.Changes, Change{ID: id, Before: before.item(id), After: after.item(id)})
+	}
+	for _, id := range removed {
+		c.Changes = append(c.Changes, Change{ID: id, Before: before.item(id)})
+	}
+	return c
+}
+
+// item returns a copy of the item with the given ID, or nil.
+func (l List) item(id uuid.UUID) *Item {
+	if it := l.find(id); it != nil {
+		found := *it
+		return &found
+	}
+	return nil
+}
+
+// String describes the command for messages like "Undid: delete "Gym"".
+func (c Command) String() string {
+	if len(c.Changes) != 1 {
+		return fmt.Sprintf("change %d tasks", len(c.Changes))
+	}
+	ch := c.Changes[0]
+	switch {
+	case ch.Before == nil:
+		return fmt.Sprintf("add %q", ch.After.Task)
+	case ch.After == nil:
+		return fmt.Sprintf("delete %q", ch.Before.Task)
+	}
+
+	b, a := ch.Before, ch.After
+	verb := "edit"
+	switch {
+	case a.Trashed() && !b.Trashed():
+		verb = "delete"
+	case b.Trashed() && !a.Trashed():
+		verb = "restore"
+	case b.Done != a.Done || doneList(b) != doneList(a):
+		verb = "toggle"
+	case !b.Date.Equal(a.Date) || b.IsSomeday != a.IsSomeday:
+		verb = "move"
+	case !reflect.DeepEqual(b.RecurrenceRule, a.RecurrenceRule):
+		verb = "change the repeat of"
+	}
+	return fmt.Sprintf("%s %q", verb, a.Task)
+}
+
+func doneList(it *Item) int {
+	if it.RecurrenceRule == nil {
+		return 0
+	}
+	return len(it.RecurrenceRule.DoneList)
+}
+
+// apply puts every changed item into l as it was before (undo) or after
+// (redo) the command.
+func (c Command) apply(l *List, undo bool) {
+	for i := range c.Changes {
+		ch := c.Changes[i]
+		want := ch.After
+		if undo {
+			// in reverse, in case an item changed more than once
+			ch = c.Changes[len(c.Changes)-1-i]
+			want = ch.Before
+		}
+		switch existing := l.find(ch.ID); {
+		case want == nil:
+			l.remove(ch.ID)
+		case existing != nil:
+			*existing = *want
+		default:
+			*l = append(*l, *want)
+		}
+	}
+}
+
+// History is the undo and redo stack of a data file. It lives in its own
+// file, so every weektcli process shares it and it survives restarts.
+type History struct {
+	filename string
+}
+
+type historyFile struct {
+	Undo []Command `json:"undo"`
+	Redo []Command `json:"redo"`
+}
+
+// NewHistory returns the history kept in filename, see HistoryName.
+func NewHistory(filename string) *History {
+	return &History{filename: filename}
+}
+
+func (h *History) Path() string { return h.filename }
+
+// Record adds the change from before to after as a command to undo and
+// clears what could be redone. Saves that changed nothing are ignored.
+func (h *History) Record(before, after List) error {
+	c := newCommand(before, after)
+	if len(c.Changes) == 0 {
+		return nil
+	}
+	return h.update(func(f *historyFile) error {
+		f.Undo = append(f.Undo, c)
+		if len(f.Undo) > HistoryLimit {
+			f.Undo = f.Undo[len(f.Undo)-HistoryLimit:]
+		}
+		f.Redo = nil
+		return nil
+	})
+}
+
+// Undo reverts the last command in l and calls save to store the result.
+// The command only moves to the redo stack once save succeeded.
+func (h *History) Undo(l *List, save func() error) (Command, error) {
+	return h.step(l, save, true)
+}
+
+// Redo applies the last undone command to l again, see Undo.
+func (h *History) Redo(l *List, save func() error) (Command, error) {
+	return h.step(l, save, false)
+}
+
+func (h *History) step(l *List, save func() error, undo bool) (Command, error) {
+	var c Command
+	err := h.update(func(f *historyFile) error {
+		from, to, empty := &f.Undo, &f.Redo, ErrNothingToUndo
+		if !undo {
+			from, to, empty = &f.Redo, &f.Undo, ErrNothingToRedo
+		}
+		if len(*from) == 0 {
+			return empty
+		}
+
+		c = (*from)[len(*from)-1]
+		c.apply(l, undo)
+		if err := save(); err != nil {
+			return err
+		}
+		*from = (*from)[:len(*from)-1]
+		*to = append(*to, c)
+		return nil
+	})
+	return c, err
+}
+
+// update applies fn to the history file while holding its lock, and writes
+// the result unless fn fails.
+func (h *History) update(fn func(*historyFile) error) error {
+	unlock, err := Lock(h.filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var f historyFile
+	data, err := os.ReadFile(h.filename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &f); err != nil {
+			return fmt.Errorf("undo history %s: %w", h.filename, err)
+		}
+	}
+
+	if err := fn(&f); err != nil {
+		return err
+	}
+	data, err = json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(h.filename, data, 0644)
+}
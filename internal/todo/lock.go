@@ -0,0 +1,25 @@
+package todo
+
+import "os"
+
+// LockName is the lock file guarding a data file.
+func LockName(filename string) string {
+	return filename + ".lock"
+}
+
+// Lock takes an exclusive advisory lock on filename, waiting until other
+// weektcli processes holding it let go. Call the returned func to release.
+func Lock(filename string) (unlock func() error, err error) {
+	f, err := os.OpenFile(LockName(filename), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() error {
+		unlockFile(f)
+		return f.Close()
+	}, nil
+}
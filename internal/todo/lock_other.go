@@ -0,0 +1,11 @@
+//go:build !unix && !windows
+
+package todo
+
+import "os"
+
+// Platforms without file locking (wasm, plan9) run unlocked.
+
+func lockFile(f *os.File) error { return nil }
+
+func unlockFile(f *os.File) error { return nil }
@@ -0,0 +1,88 @@
+package todo
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// Snapshot returns a deep copy of the list to diff local changes against
+// later on.
+func (l List) Snapshot() List {
+	data, _ := json.Marshal(l)
+	var out List
+	json.Unmarshal(data, &out)
+	return out
+}
+
+// Merge combines two edits of the same list. base is the list both sides
+// started from, local holds our changes and disk the changes another
+// process saved in the meantime. Items are compared one by one: an item
+// this process added, changed or deleted keeps our version, every other
+// item takes whatever is on disk.
+func Merge(base, local, disk List) List {
+	baseItems := encodeItems(base)
+	localItems := encodeItems(local)
+
+	changedLocally := func(id uuid.UUID) bool {
+		b, inBase := baseItems[id]
+		l, inLocal := localItems[id]
+		return inBase != inLocal || !bytes.Equal(b, l)
+	}
+
+	localByID := make(map[uuid.UUID]Item, len(local))
+	for _, item := range local {
+		localByID[item.ID] = item
+	}
+
+	merged := make(List, 0, len(disk))
+	seen := make(map[uuid.UUID]bool, len(disk))
+	for _, item := range disk {
+		seen[item.ID] = true
+		if !changedLocally(item.ID) {
+			merged = append(merged, item)
+			continue
+		}
+		if mine, ok := localByID[item.ID]; ok {
+			merged = append(merged, mine)
+		}
+		// else: deleted here, so it stays deleted
+	}
+
+	// Items only we know about: new ones, or ones we changed that were
+	// deleted elsewhere. Items we left alone that are gone from disk were
+	// deleted by the other process and stay gone.
+	for _, item := range local {
+		if !seen[item.ID] && changedLocally(item.ID) {
+			merged = append(merged, item)
+		}
+	}
+	return merged
+}
+
+func encodeItems(l List) map[uuid.UUID][]byte {
+	out := make(map[uuid.UUID][]byte, len(l))
+	for _, item := range l {
+		out[item.ID], _ = json.Marshal(item)
+	}
+	return out
+}
+
+// Diff compares two versions of a list. changed holds the items that are
+// new or different in b, removed the ones b no longer has.
+func Diff(a, b List) (changed, removed []uuid.UUID) {
+	aItems := encodeItems(a)
+	bItems := encodeItems(b)
+	for _, item := range b {
+		if prev, ok := aItems[item.ID]; !ok || !bytes.Equal(prev, bItems[item.ID]) {
+			changed = append(changed, item.ID)
+		}
+	}
+	for _, item := range a {
+		if _, ok := bItems[item.ID]; !ok {
+			removed = append(removed, item.ID)
+		}
+	}
+	return changed, removed
+}
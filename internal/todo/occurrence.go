@@ -0,0 +1,717 @@
+package todo
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DateLayout is the day-granularity format used for DoneList entries and
+// for dates passed on the command line.
+const DateLayout = "2006-01-02"
+
+// Occurrence is a single appearance of an Item on a calendar day.
+// For recurring items Done reflects the rule's DoneList for the occurrence,
+// the master Item.Done is ignored, and any Override has been applied.
+type Occurrence struct {
+	Item
+	Day time.Time `json:"day"`
+	// Original is the day the series scheduled this occurrence on. It only
+	// differs from Day when the single occurrence was moved.
+	Original time.Time `json:"original"`
+}
+
+// StartOfWeek returns the first day of the week t falls in, for weeks
+// starting on first.
+func StartOfWeek(t time.Time, first time.Weekday) time.Time {
+	offset := (int(t.Weekday()) - int(first) + 7) % 7
+	return midnight(t).AddDate(0, 0, -offset)
+}
+
+// midnight normalizes t to the start of its day so day math is not thrown
+// off by hours, minutes or DST shifts.
+func midnight(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+// daysBetween returns the whole number of calendar days from a to b.
+func daysBetween(a, b time.Time) int {
+	return int(midnight(b).Sub(midnight(a)).Hours()+12) / 24
+}
+
+// OccursOn reports whether the item shows up on the given day.
+// Someday items never occur on a date.
+func (it Item) OccursOn(day time.Time) bool {
+	_, ok := it.OccurrenceOn(day)
+	return ok
+}
+
+// OccurrenceOn returns the occurrence of the item shown on day.
+func (it Item) OccurrenceOn(day time.Time) (Occurrence, bool) {
+	occs := it.occurrencesOn(day)
+	if len(occs) == 0 {
+		return Occurrence{}, false
+	}
+	return occs[0], true
+}
+
+// occurrencesOn returns every occurrence shown on day. Usually that is zero
+// or one, but an occurrence moved onto a scheduled day makes two.
+func (it Item) occurrencesOn(day time.Time) []Occurrence {
+	if it.Trashed() {
+		return nil
+	}
+	target := midnight(day)
+
+	var out []Occurrence
+	if it.scheduledOn(target) {
+		// Skip the slot if this occurrence was moved to another day
+		ov, ok := it.override(target)
+		if !ok || ov.Date == nil || midnight(*ov.Date).Equal(target) {
+			out = append(out, it.occurrence(target, target))
+		}
+	}
+
+	if it.RecurrenceRule == nil || len(it.RecurrenceRule.Overrides) == 0 {
+		return out
+	}
+
+	// Pick up occurrences moved here from other days, in date order
+	keys := make([]string, 0, len(it.RecurrenceRule.Overrides))
+	for key := range it.RecurrenceRule.Overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		ov := it.RecurrenceRule.Overrides[key]
+		if ov.Date == nil || !midnight(*ov.Date).Equal(target) {
+			continue
+		}
+		original, err := time.ParseInLocation(DateLayout, key, time.Local)
+		if err != nil || original.Equal(target) || !it.scheduledOn(original) {
+			continue
+		}
+		out = append(out, it.occurrence(original, target))
+	}
+	return out
+}
+
+// occurrenceOf returns the occurrence the series scheduled on original,
+// also when it was moved to another day. Occurrences are addressed this
+// way because a moved one can share its day with another.
+func (it Item) occurrenceOf(original time.Time) (Occurrence, bool) {
+	original = midnight(original)
+	if it.Trashed() || !it.scheduledOn(original) {
+		return Occurrence{}, false
+	}
+	day := original
+	if ov, ok := it.override(original); ok && ov.Date != nil {
+		day = midnight(*ov.Date)
+	}
+	return it.occurrence(original, day), true
+}
+
+// override returns the per-occurrence changes for the occurrence
+// originally scheduled on day.
+func (it Item) override(day time.Time) (Override, bool) {
+	if it.RecurrenceRule == nil || it.RecurrenceRule.Overrides == nil {
+		return Override{}, false
+	}
+	ov, ok := it.RecurrenceRule.Overrides[day.Format(DateLayout)]
+	return ov, ok
+}
+
+// scheduledOn reports whether the series itself puts an occurrence on day,
+// ignoring per-occurrence moves.
+func (it Item) scheduledOn(day time.Time) bool {
+	if it.IsSomeday {
+		return false
+	}
+
+	target := midnight(day)
+	start := midnight(it.Date)
+
+	if it.RecurrenceRule == nil {
+		return start.Equal(target)
+	}
+
+	// Never show before the start date, or where the series was archived
+	if target.Before(start) {
+		return false
+	}
+	rule := it.RecurrenceRule
+	if rule.archivedOn(target.Format(DateLayout)) {
+		return false
+	}
+
+	if rule.AfterCompletion {
+		// Completions stay where they happened, plus the one pending occurrence
+		if rule.IsDoneOn(target) {
+			return true
+		}
+		due, ok := rule.completionDue(start)
+		return ok && due.Equal(target)
+	}
+
+	if rule.Until != nil && target.After(midnight(*rule.Until)) {
+		return false
+	}
+	if !rule.matches(start, target) || rule.IsSkipped(target) {
+		return false
+	}
+
+	return rule.Count == 0 || rule.countBefore(start, target) < int(rule.Count)
+}
+
+// countBefore returns how many occurrences the rule produces from start up
+// to, but not including, target. It stops counting once Count is reached.
+func (r *RecurrenceRule) countBefore(start, target time.Time) int {
+	n := 0
+	for d := start; d.Before(target) && n < int(r.Count); d = d.AddDate(0, 0, 1) {
+		if r.matches(start, d) {
+			n++
+		}
+	}
+	return n
+}
+
+// completionDue returns the day the pending occurrence of a
+// completion-relative rule is due, or false once the series has ended.
+// Skipped occurrences count as completions for scheduling.
+func (r *RecurrenceRule) completionDue(start time.Time) (time.Time, bool) {
+	if r.Count > 0 && len(r.DoneList)+int(r.Archived) >= int(r.Count) {
+		return time.Time{}, false
+	}
+
+	var anchor time.Time
+	for _, list := range [][]string{r.DoneList, r.Exceptions} {
+		for _, key := range list {
+			d, err := time.ParseInLocation(DateLayout, key, time.Local)
+			if err == nil && d.After(anchor) {
+				anchor = d
+			}
+		}
+	}
+
+	due := start
+	if !anchor.IsZero() {
+		if next := r.advance(anchor); next.After(due) {
+			due = next
+		}
+	}
+
+	if r.Until != nil && due.After(midnight(*r.Until)) {
+		return time.Time{}, false
+	}
+	return due, true
+}
+
+// advance moves d forward by one interval of the rule's frequency.
+func (r *RecurrenceRule) advance(d time.Time) time.Time {
+	interval := int(r.Interval)
+	if interval <= 0 {
+		interval = 1
+	}
+
+	switch r.Freq {
+	case Weekly:
+		return d.AddDate(0, 0, 7*interval)
+	case Monthly:
+		return d.AddDate(0, interval, 0)
+	case Yearly:
+		return d.AddDate(interval, 0, 0)
+	}
+	return d.AddDate(0, 0, interval)
+}
+
+// Ended reports whether the rule has no occurrences left after day.
+func (it Item) Ended(day time.Time) bool {
+	rule := it.RecurrenceRule
+	if rule == nil {
+		return !midnight(it.Date).After(midnight(day))
+	}
+	if rule.AfterCompletion {
+		due, ok := rule.completionDue(midnight(it.Date))
+		return !ok || !due.After(midnight(day))
+	}
+	if rule.Until != nil && !midnight(*rule.Until).After(midnight(day)) {
+		return true
+	}
+	start := midnight(it.Date)
+	next := midnight(day).AddDate(0, 0, 1)
+	return rule.Count > 0 && !next.Before(start) && rule.countBefore(start, next) >= int(rule.Count)
+}
+
+// matches checks the frequency pattern of the rule against a target day.
+// Both dates must already be normalized to midnight.
+func (r *RecurrenceRule) matches(start, target time.Time) bool {
+	interval := int(r.Interval)
+	if interval <= 0 {
+		interval = 1
+	}
+
+	daysDiff := daysBetween(start, target)
+
+	switch r.Freq {
+	case Daily:
+		return daysDiff%interval == 0
+
+	case Weekly:
+		weekdayMatch := false
+
+		// If no weekdays were selected (null/empty),
+		// default to the weekday of the task's original start date.
+		if len(r.Weekdays) == 0 {
+			weekdayMatch = target.Weekday() == start.Weekday()
+		} else {
+			for _, wd := range r.Weekdays {
+				if wd == target.Weekday() {
+					weekdayMatch = true
+					break
+				}
+			}
+		}
+
+		return weekdayMatch && (daysDiff/7)%interval == 0
+
+	case Monthly:
+		startYear, startMonth, startDay := start.Date()
+		targetYear, targetMonth, _ := target.Date()
+
+		// Formula for total months difference: (YearDiff * 12) + MonthDiff
+		monthsDiff := (targetYear-startYear)*12 + int(targetMonth-startMonth)
+		if monthsDiff < 0 || monthsDiff%interval != 0 {
+			return false
+		}
+
+		if r.Nth != 0 {
+			return r.matchesNthWeekday(start, target)
+		}
+		return r.matchesMonthDay(startDay, target)
+
+	case Yearly:
+		startYear, startMonth, startDay := start.Date()
+		targetYear, targetMonth, _ := target.Date()
+
+		// Yearly tasks stay in the month of the start date
+		yearsDiff := targetYear - startYear
+		if targetMonth != startMonth || yearsDiff < 0 || yearsDiff%interval != 0 {
+			return false
+		}
+
+		if r.Nth != 0 {
+			return r.matchesNthWeekday(start, target)
+		}
+		return r.matchesMonthDay(startDay, target)
+	}
+
+	return false
+}
+
+// matchesMonthDay checks the day of month of target against MonthDay.
+func (r *RecurrenceRule) matchesMonthDay(startDay int, target time.Time) bool {
+	targetYear, targetMonth, targetDay := target.Date()
+
+	// If MonthDay is 0 (default), use the day from the task's original start date
+	matchDay := int(r.MonthDay)
+	if matchDay <= 0 {
+		matchDay = startDay
+	}
+
+	if targetDay == matchDay {
+		return true
+	}
+
+	// "End of month" safety: a task set for the 31st shows up on the
+	// last day of shorter months.
+	lastDayOfMonth := time.Date(targetYear, targetMonth+1, 0, 0, 0, 0, 0, time.Local).Day()
+	return matchDay > lastDayOfMonth && targetDay == lastDayOfMonth
+}
+
+// matchesNthWeekday checks target is the Nth (or last) of its weekday in
+// the month and that weekday is one the rule asks for.
+func (r *RecurrenceRule) matchesNthWeekday(start, target time.Time) bool {
+	weekdays := r.Weekdays
+	if len(weekdays) == 0 {
+		weekdays = []time.Weekday{start.Weekday()}
+	}
+
+	weekdayMatch := false
+	for _, wd := range weekdays {
+		if wd == target.Weekday() {
+			weekdayMatch = true
+			break
+		}
+	}
+	if !weekdayMatch {
+		return false
+	}
+
+	if r.Nth < 0 {
+		return IsLastWeekdayOfMonth(target)
+	}
+	return NthWeekdayOfMonth(target) == int(r.Nth)
+}
+
+// NthWeekdayOfMonth returns which occurrence of its weekday t is within
+// its month, 1 for the first Monday, 2 for the second and so on.
+func NthWeekdayOfMonth(t time.Time) int {
+	return (t.Day()-1)/7 + 1
+}
+
+// IsLastWeekdayOfMonth reports whether no later day in the month shares
+// the weekday of t.
+func IsLastWeekdayOfMonth(t time.Time) bool {
+	return t.AddDate(0, 0, 7).Month() != t.Month()
+}
+
+// IsDoneOn reports whether the occurrence on day has been checked off.
+func (r *RecurrenceRule) IsDoneOn(day time.Time) bool {
+	key := day.Format(DateLayout)
+	for _, d := range r.DoneList {
+		if d == key {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSkipped reports whether the occurrence on day was cancelled.
+func (r *RecurrenceRule) IsSkipped(day time.Time) bool {
+	key := day.Format(DateLayout)
+	for _, d := range r.Exceptions {
+		if d == key {
+			return true
+		}
+	}
+	return false
+}
+
+// occurrence builds the concrete instance of it scheduled on original and
+// shown on day.
+func (it Item) occurrence(original, day time.Time) Occurrence {
+	if it.RecurrenceRule != nil {
+		// The master 'Done' is ignored for recurring instances
+		it.Done = it.RecurrenceRule.IsDoneOn(original)
+
+		if ov, ok := it.override(original); ok {
+			if ov.Task != nil {
+				it.Task = *ov.Task
+			}
+			if ov.Notes != nil {
+				it.Notes = *ov.Notes
+			}
+		}
+	}
+	return Occurrence{Item: it, Day: midnight(day), Original: midnight(original)}
+}
+
+// OccurrencesOn returns every item that shows up on day, in list order.
+func (l List) OccurrencesOn(day time.Time) []Occurrence {
+	var out []Occurrence
+	for _, it := range l {
+		out = append(out, it.occurrencesOn(day)...)
+	}
+	return out
+}
+
+// Occurrences expands the list into concrete instances for every day in
+// the inclusive range [from, to]. Results are ordered by day, then by the
+// position of the item in the list. Someday items are not included.
+func (l List) Occurrences(from, to time.Time) []Occurrence {
+	var out []Occurrence
+	for d := midnight(from); !d.After(midnight(to)); d = d.AddDate(0, 0, 1) {
+		out = append(out, l.OccurrencesOn(d)...)
+	}
+	return out
+}
+
+// Someday returns the items parked in the Someday drawer.
+func (l List) Someday() []Item {
+	var out []Item
+	for _, it := range l {
+		if it.IsSomeday && !it.Trashed() {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// NextOccurrence returns the first day on or after from that the item
+// shows up on. It gives up after looking ten years ahead. For tasks that
+// repeat after completion it is the pending due date, which may be
+// earlier than from when the task is overdue.
+func (it Item) NextOccurrence(from time.Time) (time.Time, bool) {
+	if it.IsSomeday {
+		return time.Time{}, false
+	}
+
+	if rule := it.RecurrenceRule; rule != nil && rule.AfterCompletion {
+		return rule.completionDue(midnight(it.Date))
+	}
+
+	d := midnight(from)
+	if start := midnight(it.Date); d.Before(start) {
+		d = start
+	}
+
+	limit := d.AddDate(10, 0, 0)
+	if rule := it.RecurrenceRule; rule != nil && rule.Until != nil && rule.Until.Before(limit) {
+		limit = midnight(*rule.Until)
+	}
+
+	for ; !d.After(limit); d = d.AddDate(0, 0, 1) {
+		if it.OccursOn(d) {
+			return d, true
+		}
+		if it.RecurrenceRule == nil || it.Ended(d) {
+			break
+		}
+	}
+	return time.Time{}, false
+}
+
+// OverdueOccurrences returns what was due before today and is not done:
+// the overdue one-time tasks, see Overdue, and the pending occurrence of
+// tasks that repeat after completion. Missed occurrences of tasks on a
+// fixed schedule are left out, the next occurrence takes their place.
+// The result is ordered by day.
+func (l List) OverdueOccurrences(today time.Time) []Occurrence {
+	today = midnight(today)
+	var out []Occurrence
+	for _, it := range l {
+		if it.needsRollover(today) {
+			out = append(out, it.occurrence(it.Date, it.Date))
+			continue
+		}
+		rule := it.RecurrenceRule
+		if rule == nil || !rule.AfterCompletion || it.IsSomeday || it.Trashed() {
+			continue
+		}
+		if due, ok := rule.completionDue(midnight(it.Date)); ok && due.Before(today) {
+			out = append(out, it.occurrence(due, due))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Day.Before(out[j].Day) })
+	return out
+}
+
+// ToggleOccurrence flips the done state of the occurrence originally
+// scheduled on day, see Occurrence.Original, wherever it shows up now.
+// One-time tasks flip their own Done flag, recurring tasks add or remove
+// day from the rule's DoneList.
+func (l *List) ToggleOccurrence(id uuid.UUID, day time.Time) (Occurrence, error) {
+	for i := range *l {
+		item := &(*l)[i]
+		if item.ID != id {
+			continue
+		}
+
+		if item.RecurrenceRule == nil {
+			item.Done = !item.Done
+			item.CompletedAt = nil
+			if item.Done {
+				now := time.Now()
+				item.CompletedAt = &now
+			}
+			return item.occurrence(item.Date, item.Date), nil
+		}
+
+		if item.RecurrenceRule.AfterCompletion {
+			return item.toggleCompletion(day)
+		}
+
+		occ, ok := item.occurrenceOf(day)
+		if !ok {
+			return Occurrence{}, fmt.Errorf("task %s does not occur on %s", id, day.Format(DateLayout))
+		}
+
+		key := occ.Original.Format(DateLayout)
+		rule := item.RecurrenceRule
+
+		foundIdx := -1
+		for idx, date := range rule.DoneList {
+			if date == key {
+				foundIdx = idx
+				break
+			}
+		}
+
+		if foundIdx != -1 {
+			// Date found: Uncheck it (Remove from list)
+			rule.DoneList = append(rule.DoneList[:foundIdx], rule.DoneList[foundIdx+1:]...)
+		} else {
+			// Date not found: Check it (Add to list)
+			rule.DoneList = append(rule.DoneList, key)
+		}
+
+		return item.occurrence(occ.Original, occ.Day), nil
+	}
+	return Occurrence{}, fmt.Errorf("task with ID %s not found", id)
+}
+
+// SkipOccurrence cancels the occurrence of a recurring task originally
+// scheduled on day, the rest of the series is left untouched.
+func (l *List) SkipOccurrence(id uuid.UUID, day time.Time) error {
+	item, occ, err := l.recurringOn(id, day)
+	if err != nil {
+		return err
+	}
+
+	key := occ.Original.Format(DateLayout)
+	rule := item.RecurrenceRule
+	rule.Exceptions = append(rule.Exceptions, key)
+	delete(rule.Overrides, key)
+
+	// A skipped day can't stay checked off
+	for i, d := range rule.DoneList {
+		if d == key {
+			rule.DoneList = append(rule.DoneList[:i], rule.DoneList[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// EndSeriesBefore stops a recurring task so the occurrence originally
+// scheduled on day and every later one disappear. Cutting at the first
+// occurrence moves the whole series to the trash.
+func (l *List) EndSeriesBefore(id uuid.UUID, day time.Time) error {
+	item, occ, err := l.recurringOn(id, day)
+	if err != nil {
+		return err
+	}
+
+	if !occ.Original.After(midnight(item.Date)) {
+		return l.DeleteTask(id.String())
+	}
+
+	// The occurrence exists, so a count has not run out before it and the
+	// last day takes over from it
+	rule := *item.RecurrenceRule
+	until := occ.Original.AddDate(0, 0, -1)
+	if rule.Until == nil || until.Before(*rule.Until) {
+		rule.Until = &until
+	}
+	rule.Count = 0
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	item.RecurrenceRule = &rule
+	return nil
+}
+
+// recurringOn finds a recurring item and its occurrence originally
+// scheduled on day.
+func (l *List) recurringOn(id uuid.UUID, day time.Time) (*Item, Occurrence, error) {
+	for i := range *l {
+		item := &(*l)[i]
+		if item.ID != id {
+			continue
+		}
+		if item.RecurrenceRule == nil {
+			return nil, Occurrence{}, fmt.Errorf("task %s does not repeat", id)
+		}
+		occ, ok := item.occurrenceOf(day)
+		if !ok {
+			return nil, Occurrence{}, fmt.Errorf("task %s does not occur on %s", id, day.Format(DateLayout))
+		}
+		return item, occ, nil
+	}
+	return nil, Occurrence{}, fmt.Errorf("task with ID %s not found", id)
+}
+
+// UpdateOccurrence changes the title and notes of the single occurrence
+// originally scheduled on day, leaving the rest of the series alone.
+func (l *List) UpdateOccurrence(id uuid.UUID, day time.Time, newTaskName, newNotes string) error {
+	item, occ, err := l.recurringOn(id, day)
+	if err != nil {
+		return err
+	}
+
+	item.setOverride(occ.Original, func(ov *Override) {
+		ov.Task = &newTaskName
+		ov.Notes = &newNotes
+		if newTaskName == item.Task {
+			ov.Task = nil
+		}
+		if newNotes == item.Notes {
+			ov.Notes = nil
+		}
+	})
+	return nil
+}
+
+// MoveOccurrence reschedules the single occurrence originally scheduled on
+// day to newDate.
+func (l *List) MoveOccurrence(id uuid.UUID, day, newDate time.Time) error {
+	item, occ, err := l.recurringOn(id, day)
+	if err != nil {
+		return err
+	}
+
+	target := midnight(newDate)
+	item.setOverride(occ.Original, func(ov *Override) {
+		ov.Date = &target
+		if target.Equal(occ.Original) {
+			ov.Date = nil
+		}
+	})
+	return nil
+}
+
+// setOverride edits the override for the occurrence scheduled on original
+// and drops it again once it no longer changes anything.
+func (it *Item) setOverride(original time.Time, edit func(*Override)) {
+	rule := it.RecurrenceRule
+	key := original.Format(DateLayout)
+
+	ov := rule.Overrides[key]
+	edit(&ov)
+
+	if ov.Task == nil && ov.Notes == nil && ov.Date == nil {
+		delete(rule.Overrides, key)
+		return
+	}
+	if rule.Overrides == nil {
+		rule.Overrides = map[string]Override{}
+	}
+	rule.Overrides[key] = ov
+}
+
+// toggleCompletion checks off the pending occurrence of a completion-relative
+// task, or unchecks the completion recorded on day. A completion is recorded
+// on day but never later than today, since it decides when the task is due next.
+func (it *Item) toggleCompletion(day time.Time) (Occurrence, error) {
+	rule := it.RecurrenceRule
+	key := day.Format(DateLayout)
+
+	for idx, date := range rule.DoneList {
+		if date == key {
+			rule.DoneList = append(rule.DoneList[:idx], rule.DoneList[idx+1:]...)
+			return it.occurrence(day, day), nil
+		}
+	}
+
+	start := midnight(it.Date)
+	if _, ok := rule.completionDue(start); !ok {
+		return Occurrence{}, fmt.Errorf("task %s has no occurrences left", it.ID)
+	}
+
+	done := midnight(day)
+	if today := midnight(time.Now()); done.After(today) {
+		done = today
+	}
+	if done.Before(start) {
+		done = start
+	}
+
+	if !rule.IsDoneOn(done) {
+		rule.DoneList = append(rule.DoneList, done.Format(DateLayout))
+	}
+	return it.occurrence(done, done), nil
+}
@@ -0,0 +1,101 @@
+package todo
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestOccurrences(t *testing.T) {
+	tests := []struct {
+		name     string
+		start    string
+		rule     RecurrenceRule
+		from, to string
+		want     []string
+	}{
+		{
+			name:  "count",
+			start: "2026-10-01",
+			rule:  RecurrenceRule{Freq: Daily, Interval: 1, Count: 3},
+			from:  "2026-09-28", to: "2026-10-10",
+			want: []string{"2026-10-01", "2026-10-02", "2026-10-03"},
+		},
+		{
+			name:  "skipped days count towards the count",
+			start: "2026-10-01",
+			rule:  RecurrenceRule{Freq: Daily, Interval: 1, Count: 3, Exceptions: []string{"2026-10-02"}},
+			from:  "2026-09-28", to: "2026-10-10",
+			want: []string{"2026-10-01", "2026-10-03"},
+		},
+		{
+			name:  "until is the last day",
+			start: "2026-10-06",
+			rule:  RecurrenceRule{Freq: Weekly, Interval: 1, Until: ptr(day(t, "2026-10-20"))},
+			from:  "2026-10-01", to: "2026-11-30",
+			want: []string{"2026-10-06", "2026-10-13", "2026-10-20"},
+		},
+		{
+			name:  "nth weekday",
+			start: "2026-10-13",
+			rule:  RecurrenceRule{Freq: Monthly, Interval: 1, Nth: 2, Weekdays: []time.Weekday{time.Tuesday}},
+			from:  "2026-10-01", to: "2027-01-31",
+			want: []string{"2026-10-13", "2026-11-10", "2026-12-08", "2027-01-12"},
+		},
+		{
+			name:  "nth weekday without weekdays uses the start's",
+			start: "2026-10-13",
+			rule:  RecurrenceRule{Freq: Monthly, Interval: 1, Nth: 2},
+			from:  "2026-10-01", to: "2027-01-31",
+			want: []string{"2026-10-13", "2026-11-10", "2026-12-08", "2027-01-12"},
+		},
+		{
+			name:  "last weekday",
+			start: "2026-10-30",
+			rule:  RecurrenceRule{Freq: Monthly, Interval: 1, Nth: -1, Weekdays: []time.Weekday{time.Friday}, Count: 3},
+			from:  "2026-10-01", to: "2027-03-31",
+			want: []string{"2026-10-30", "2026-11-27", "2026-12-25"},
+		},
+		{
+			name:  "archived days stay hidden, missed ones stay",
+			start: "2026-10-01",
+			rule:  RecurrenceRule{Freq: Daily, Interval: 1, Until: ptr(day(t, "2026-10-06")), ArchivedRuns: []string{"2026-10-01/2026-10-02", "2026-10-04/2026-10-05"}},
+			from:  "2026-10-01", to: "2026-10-10",
+			want: []string{"2026-10-03", "2026-10-06"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := tt.rule
+			l := List{{ID: uuid.New(), Task: "Gym", Date: day(t, tt.start), RecurrenceRule: &rule}}
+
+			var got []string
+			for _, occ := range l.Occurrences(day(t, tt.from), day(t, tt.to)) {
+				got = append(got, occ.Day.Format(DateLayout))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("occurrences = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndSeriesBeforeDropsCount(t *testing.T) {
+	rule := RecurrenceRule{Freq: Daily, Interval: 1, Count: 5}
+	id := uuid.New()
+	l := List{{ID: id, Task: "Gym", Date: day(t, "2026-10-01"), RecurrenceRule: &rule}}
+
+	if err := l.EndSeriesBefore(id, day(t, "2026-10-03")); err != nil {
+		t.Fatal(err)
+	}
+	got := l[0].RecurrenceRule
+	if got.Count != 0 || got.Until == nil || got.Until.Format(DateLayout) != "2026-10-02" {
+		t.Errorf("rule after EndSeriesBefore = count %d, until %v; want until 2026-10-02 only", got.Count, got.Until)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
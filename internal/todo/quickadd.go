@@ -0,0 +1,208 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var priorityNames = []string{"none", "low", "medium", "high"}
+
+func (p Priority) String() string {
+	if int(p) < len(priorityNames) {
+		return priorityNames[p]
+	}
+	return fmt.Sprintf("Priority(%d)", p)
+}
+
+// ParsePriority converts "low", "medium" or "high" into a Priority. "med"
+// and the first letters are accepted too.
+func ParsePriority(s string) (Priority, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "med" {
+		return Medium, nil
+	}
+	for i, name := range priorityNames {
+		if s != "" && (s == name || s == name[:1]) {
+			return Priority(i), nil
+		}
+	}
+	return NoPriority, fmt.Errorf("unknown priority %q, expected low, medium or high", s)
+}
+
+// AddItem adds a task built elsewhere, e.g. by ParseQuickAdd, under a new
+// ID.
+func (l *List) AddItem(it Item) Item {
+	it.ID = uuid.New()
+	*l = append(*l, it)
+	return it
+}
+
+// ParseQuickAdd pulls the markers out of a task typed in one line, like
+// "Pay rent ^1st !high #home every month":
+//
+//	^<date>    the day, anything ParseDate takes: ^fri, ^next monday, ^someday
+//	!<level>   the priority: !low, !medium or !high
+//	#<tag>     a tag, as many as needed
+//	every ...  how it repeats: every day, every 2 weeks, every other month,
+//	           every weekday, every mon,thu
+//
+// The other words become the Task of the returned item, and so does a ^ or
+// ! word that is no date or priority, like "Ping ^jon". dated reports
+// whether there was a ^date; without one Date is zero and the caller picks
+// the day.
+func ParseQuickAdd(s string, now time.Time, first time.Weekday) (it Item, dated bool, err error) {
+	words := strings.Fields(s)
+	var title []string
+
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		switch {
+		case strings.HasPrefix(w, "^") && len(w) > 1:
+			if dated {
+				return Item{}, false, fmt.Errorf("%s: the task already has a date", w)
+			}
+			n, day, someday, err := quickDate(words[i:], now, first)
+			if err != nil {
+				title = append(title, w)
+				continue
+			}
+			it.Date, it.IsSomeday, dated = day, someday, true
+			i += n - 1
+
+		case strings.HasPrefix(w, "!") && len(w) > 1:
+			p, err := ParsePriority(w[1:])
+			if err != nil {
+				title = append(title, w)
+				continue
+			}
+			it.Priority = p
+
+		case strings.HasPrefix(w, "#") && len(w) > 1 && !isNumber(w[1:]):
+			if !contains(it.Tags, w[1:]) {
+				it.Tags = append(it.Tags, w[1:])
+			}
+
+		case strings.EqualFold(w, "every") && it.RecurrenceRule == nil:
+			rule, n, ok := quickRule(words[i+1:])
+			if !ok {
+				title = append(title, w)
+				continue
+			}
+			if err := rule.Validate(); err != nil {
+				return Item{}, false, err
+			}
+			it.RecurrenceRule = &rule
+			i += n
+
+		default:
+			title = append(title, w)
+		}
+	}
+
+	it.Task = strings.Join(title, " ")
+	if it.Task == "" {
+		return Item{}, false, errors.New("the task has no title")
+	}
+	if it.IsSomeday && it.RecurrenceRule != nil {
+		return Item{}, false, errors.New("Someday tasks can't repeat")
+	}
+	return it, dated, nil
+}
+
+// quickDate reads the ^date starting at words[0], taking as many of the
+// following words as still make a date, and returns how many it used.
+func quickDate(words []string, now time.Time, first time.Weekday) (int, time.Time, bool, error) {
+	for n := min(len(words), 4); n > 0; n-- {
+		expr := strings.TrimPrefix(strings.Join(words[:n], " "), "^")
+		if day, someday, err := ParseDate(expr, now, first); err == nil {
+			return n, day, someday, nil
+		}
+	}
+	_, _, err := ParseDate(strings.TrimPrefix(words[0], "^"), now, first)
+	return 0, time.Time{}, false, err
+}
+
+var quickUnits = map[string]Frequency{"day": Daily, "week": Weekly, "month": Monthly, "year": Yearly}
+
+// quickRule reads what follows "every" and returns the rule and how many
+// words it used. ok is false when the words don't describe a repeat, so
+// "every" stays part of the title.
+func quickRule(words []string) (rule RecurrenceRule, n int, ok bool) {
+	rule.Interval = 1
+	if len(words) == 0 {
+		return rule, 0, false
+	}
+
+	w := strings.ToLower(words[0])
+	if w == "other" || isNumber(w) {
+		interval := 2
+		if w != "other" {
+			interval, _ = strconv.Atoi(w)
+		}
+		if interval < 1 || interval > 255 || len(words) < 2 {
+			return rule, 0, false
+		}
+		rule.Interval = uint8(interval)
+		words, n = words[1:], 1
+		w = strings.ToLower(words[0])
+	}
+
+	if freq, found := quickUnits[strings.TrimSuffix(w, "s")]; found {
+		rule.Freq = freq
+		return rule, n + 1, true
+	}
+	if n == 0 && (w == "weekday" || w == "weekdays") {
+		rule.Freq = Weekly
+		rule.Weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+		return rule, 1, true
+	}
+
+	// weekdays: "mon,thu", "mon, thu" or "mon and thu"
+	used := 0
+	for i, word := range words {
+		if i > 0 && strings.EqualFold(word, "and") {
+			continue
+		}
+		var days []time.Weekday
+		for _, part := range strings.Split(word, ",") {
+			if part == "" {
+				continue
+			}
+			wd, err := ParseWeekday(part)
+			if err != nil || len(part) < 3 {
+				days = nil
+				break
+			}
+			days = append(days, wd)
+		}
+		if days == nil {
+			break
+		}
+		rule.Weekdays = append(rule.Weekdays, days...)
+		used = i + 1
+	}
+	if used == 0 {
+		return rule, 0, false
+	}
+	rule.Freq = Weekly
+	return rule, n + used, true
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package todo
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseQuickAdd(t *testing.T) {
+	// A Wednesday
+	now := day(t, "2026-10-14")
+
+	tests := []struct {
+		in       string
+		task     string
+		date     string
+		priority Priority
+		tags     []string
+		freq     Frequency
+	}{
+		{in: "Buy milk", task: "Buy milk"},
+		{in: "Pay rent ^fri !high #home", task: "Pay rent", date: "2026-10-16", priority: High, tags: []string{"home"}},
+		{in: "Call mum ^next monday", task: "Call mum", date: "2026-10-19"},
+		{in: "Gym every week !low", task: "Gym", priority: Low, freq: Weekly},
+		{in: "Ship it !asap", task: "Ship it !asap"},
+		{in: "Ping ^jon", task: "Ping ^jon"},
+		{in: "Ping ^jon !high ^fri", task: "Ping ^jon", date: "2026-10-16", priority: High},
+		{in: "Fix issue #12", task: "Fix issue #12"},
+		{in: "Tell everyone", task: "Tell everyone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			it, dated, err := ParseQuickAdd(tt.in, now, time.Monday)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if it.Task != tt.task {
+				t.Errorf("Task = %q, want %q", it.Task, tt.task)
+			}
+			if date := it.Date.Format(DateLayout); dated != (tt.date != "") || dated && date != tt.date {
+				t.Errorf("date = %s (dated %v), want %q", date, dated, tt.date)
+			}
+			if it.Priority != tt.priority {
+				t.Errorf("Priority = %v, want %v", it.Priority, tt.priority)
+			}
+			if !slices.Equal(it.Tags, tt.tags) {
+				t.Errorf("Tags = %v, want %v", it.Tags, tt.tags)
+			}
+			freq := None
+			if it.RecurrenceRule != nil {
+				freq = it.RecurrenceRule.Freq
+			}
+			if freq != tt.freq {
+				t.Errorf("Freq = %v, want %v", freq, tt.freq)
+			}
+		})
+	}
+}
+
+func TestParseQuickAddRejects(t *testing.T) {
+	tests := []string{
+		"!high #home",
+		"Lunch ^fri ^mon",
+		"Read ^someday every day",
+	}
+	for _, in := range tests {
+		if _, _, err := ParseQuickAdd(in, day(t, "2026-10-14"), time.Monday); err == nil {
+			t.Errorf("ParseQuickAdd(%q) succeeded, want an error", in)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package todo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var frequencyNames = []string{"none", "daily", "weekly", "monthly", "yearly"}
+
+func (f Frequency) String() string {
+	if int(f) < len(frequencyNames) {
+		return frequencyNames[f]
+	}
+	return fmt.Sprintf("Frequency(%d)", f)
+}
+
+// ParseFrequency converts a name like "weekly" into a Frequency.
+func ParseFrequency(s string) (Frequency, error) {
+	for i, name := range frequencyNames {
+		if strings.EqualFold(s, name) {
+			return Frequency(i), nil
+		}
+	}
+	return None, fmt.Errorf("unknown frequency %q, expected one of %s", s, strings.Join(frequencyNames, ", "))
+}
+
+// ParseWeekday accepts full or abbreviated English weekday names ("mon", "Monday").
+func ParseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) >= 2 {
+		for wd := time.Sunday; wd <= time.Saturday; wd++ {
+			if strings.HasPrefix(strings.ToLower(wd.String()), s) {
+				return wd, nil
+			}
+		}
+	}
+	return time.Sunday, fmt.Errorf("unknown weekday %q", s)
+}
+
+// ParseWeekdays parses a comma separated list of weekdays ("mon,wed,fri").
+func ParseWeekdays(s string) ([]time.Weekday, error) {
+	var out []time.Weekday
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		wd, err := ParseWeekday(part)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, wd)
+	}
+	return out, nil
+}
+
+// Describe renders the rule as a short human readable sentence,
+// e.g. "every 2 weeks on Mon, Wed until 2026-06-30".
+func (r RecurrenceRule) Describe() string {
+	if r.Freq == None {
+		return "does not repeat"
+	}
+
+	interval := int(r.Interval)
+	if interval <= 0 {
+		interval = 1
+	}
+
+	units := map[Frequency]string{Daily: "day", Weekly: "week", Monthly: "month", Yearly: "year"}
+	desc := "every " + units[r.Freq]
+	if interval > 1 {
+		desc = fmt.Sprintf("every %d %ss", interval, units[r.Freq])
+	}
+
+	if r.AfterCompletion {
+		desc += " after completion"
+	}
+
+	switch {
+	case r.AfterCompletion:
+	case r.Freq == Weekly:
+		if len(r.Weekdays) > 0 {
+			var days []string
+			for _, wd := range r.Weekdays {
+				days = append(days, wd.String()[:3])
+			}
+			desc += " on " + strings.Join(days, ", ")
+		}
+	case r.Freq == Monthly || r.Freq == Yearly:
+		if r.Nth != 0 {
+			var days []string
+			for _, wd := range r.Weekdays {
+				days = append(days, wd.String()[:3])
+			}
+			if len(days) == 0 {
+				days = []string{"weekday"}
+			}
+			desc += fmt.Sprintf(" on the %s %s", OrdinalName(int(r.Nth)), strings.Join(days, ", "))
+		} else if r.MonthDay > 0 {
+			desc += fmt.Sprintf(" on day %d", r.MonthDay)
+		}
+	}
+
+	if r.Until != nil {
+		desc += " until " + r.Until.Format(DateLayout)
+	}
+	switch {
+	case r.Count == 1:
+		desc += ", once"
+	case r.Count > 1:
+		desc += fmt.Sprintf(", %d times", r.Count)
+	}
+	return desc
+}
+
+// Clone returns a deep copy of the rule so edits don't leak into the
+// item it was taken from.
+func (r RecurrenceRule) Clone() RecurrenceRule {
+	r.Weekdays = append([]time.Weekday{}, r.Weekdays...)
+	r.DoneList = append([]string{}, r.DoneList...)
+	r.Exceptions = append([]string(nil), r.Exceptions...)
+	r.ArchivedRuns = append([]string(nil), r.ArchivedRuns...)
+	if r.Until != nil {
+		until := *r.Until
+		r.Until = &until
+	}
+	if r.Overrides != nil {
+		overrides := make(map[string]Override, len(r.Overrides))
+		for k, v := range r.Overrides {
+			overrides[k] = v
+		}
+		r.Overrides = overrides
+	}
+	return r
+}
+
+// OrdinalName spells out an Nth position: "1st", "2nd", ... or "last" for -1.
+func OrdinalName(n int) string {
+	switch {
+	case n < 0:
+		return "last"
+	case n%10 == 1 && n != 11:
+		return fmt.Sprintf("%dst", n)
+	case n%10 == 2 && n != 12:
+		return fmt.Sprintf("%dnd", n)
+	case n%10 == 3 && n != 13:
+		return fmt.Sprintf("%drd", n)
+	}
+	return fmt.Sprintf("%dth", n)
+}
+
+// Validate checks the rule only uses values the occurrence engine understands.
+func (r RecurrenceRule) Validate() error {
+	if r.Freq > Yearly {
+		return fmt.Errorf("unknown frequency %d", r.Freq)
+	}
+	if r.MonthDay > 31 {
+		return fmt.Errorf("month day %d out of range 1-31", r.MonthDay)
+	}
+	if r.Nth != 0 {
+		if r.Freq != Monthly && r.Freq != Yearly {
+			return fmt.Errorf("nth weekday only applies to monthly or yearly tasks")
+		}
+		if r.Nth < -1 || r.Nth > 5 {
+			return fmt.Errorf("nth weekday %d out of range, use 1-5 or -1 for the last one", r.Nth)
+		}
+	}
+	if r.Count > 0 && r.Until != nil {
+		return fmt.Errorf("a task stops either after a count or on a day, not both")
+	}
+	return nil
+}
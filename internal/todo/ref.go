@@ -0,0 +1,217 @@
+package todo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MinPrefix is the shortest ID prefix a reference is matched against, so a
+// short word or number is never taken for an ID.
+const MinPrefix = 4
+
+// ListingName is the file the rows of the last `list` of the data file
+// filename are kept in, for references by ordinal.
+func ListingName(filename string) string {
+	return filename + ".listing"
+}
+
+// Listed is one numbered row of a listing. Day is set for tasks listed on
+// a day, so an ordinal picks that occurrence of a recurring task. Original
+// is set for an occurrence moved there from another day, see
+// Occurrence.Original.
+type Listed struct {
+	ID       uuid.UUID `json:"id"`
+	Day      string    `json:"day,omitempty"`
+	Original string    `json:"original,omitempty"`
+}
+
+// SaveListing writes the rows of a listing to filename, see ListingName.
+func SaveListing(filename string, rows []Listed) error {
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, data, 0644)
+}
+
+// LoadListing reads the rows SaveListing wrote. Without a listing there are
+// no rows and no error.
+func LoadListing(filename string) ([]Listed, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rows []Listed
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("last listing %s: %w", filename, err)
+	}
+	return rows, nil
+}
+
+// AmbiguousError is returned when a reference matches more than one task,
+// or only loosely matches titles, see Resolve.
+type AmbiguousError struct {
+	Ref     string
+	Matches []Item
+	Loose   bool
+}
+
+func (e *AmbiguousError) Error() string {
+	names := make([]string, len(e.Matches))
+	for i, it := range e.Matches {
+		names[i] = fmt.Sprintf("%s (%s)", it.Task, it.ID.String()[:8])
+	}
+	if e.Loose {
+		return fmt.Sprintf("no title contains %q, it only loosely matches %s; use more of the title or an ID prefix", e.Ref, strings.Join(names, ", "))
+	}
+	return fmt.Sprintf("%q matches %d tasks: %s; use an ID prefix instead", e.Ref, len(e.Matches), strings.Join(names, ", "))
+}
+
+// Resolve finds the task among items that ref refers to. In order it tries
+// an ordinal of the last listing, a full ID or a unique ID prefix of at
+// least MinPrefix characters, and then the titles: an exact title before
+// a title starting with ref, containing it, containing all of its words,
+// or containing its letters in order. The last one is a guess: it only
+// picks a task when loose is set, for lookups that change nothing, and is
+// an AmbiguousError listing the candidates otherwise. For ordinals the day is the one the
+// listed occurrence was originally scheduled on, see Occurrence.Original,
+// and zero otherwise.
+func Resolve(items []Item, ref string, listing []Listed, loose bool) (Item, time.Time, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return Item{}, time.Time{}, errors.New("no task given")
+	}
+
+	if n, err := strconv.Atoi(ref); err == nil && n >= 1 && n <= len(listing) {
+		row := listing[n-1]
+		for _, it := range items {
+			if it.ID == row.ID {
+				day := row.Day
+				if row.Original != "" {
+					day = row.Original
+				}
+				original, _ := time.ParseInLocation(DateLayout, day, time.Local)
+				return it, original, nil
+			}
+		}
+		return Item{}, time.Time{}, fmt.Errorf("task #%d of the last list not found, run weektcli list again", n)
+	}
+
+	if matches := matchID(items, ref); len(matches) == 1 {
+		return matches[0], time.Time{}, nil
+	} else if len(matches) > 1 {
+		return Item{}, time.Time{}, &AmbiguousError{Ref: ref, Matches: matches}
+	}
+
+	matches, guessed := matchTitle(items, ref)
+	if guessed && !loose {
+		return Item{}, time.Time{}, &AmbiguousError{Ref: ref, Matches: matches, Loose: true}
+	}
+	switch len(matches) {
+	case 0:
+		if _, err := strconv.Atoi(ref); err == nil {
+			if len(listing) == 0 {
+				return Item{}, time.Time{}, fmt.Errorf("no task %q, run weektcli list to number the tasks", ref)
+			}
+			return Item{}, time.Time{}, fmt.Errorf("no task #%s, the last list had %d", ref, len(listing))
+		}
+		return Item{}, time.Time{}, fmt.Errorf("no task matches %q", ref)
+	case 1:
+		return matches[0], time.Time{}, nil
+	default:
+		return Item{}, time.Time{}, &AmbiguousError{Ref: ref, Matches: matches}
+	}
+}
+
+func matchID(items []Item, ref string) []Item {
+	if len(ref) < MinPrefix {
+		return nil
+	}
+	ref = strings.ToLower(ref)
+	var matches []Item
+	for _, it := range items {
+		id := it.ID.String()
+		if id == ref {
+			return []Item{it}
+		}
+		if strings.HasPrefix(id, ref) {
+			matches = append(matches, it)
+		}
+	}
+	return matches
+}
+
+// matchTitle returns the items of the best kind of title match, see
+// Resolve. guessed is set when only the letters of ref matched.
+func matchTitle(items []Item, ref string) (matches []Item, guessed bool) {
+	ref = strings.ToLower(ref)
+	words := strings.Fields(ref)
+	tests := []func(title string) bool{
+		func(title string) bool { return title == ref },
+		func(title string) bool { return strings.HasPrefix(title, ref) },
+		func(title string) bool { return strings.Contains(title, ref) },
+		func(title string) bool {
+			for _, w := range words {
+				if !strings.Contains(title, w) {
+					return false
+				}
+			}
+			return true
+		},
+		func(title string) bool { return subsequence(title, ref) },
+	}
+	for i, test := range tests {
+		for _, it := range items {
+			if test(strings.ToLower(strings.TrimSpace(it.Task))) {
+				matches = append(matches, it)
+			}
+		}
+		if len(matches) > 0 {
+			return matches, i == len(tests)-1
+		}
+	}
+	return nil, false
+}
+
+// subsequence reports whether the letters of sub appear in s in order,
+// ignoring spaces in sub.
+func subsequence(s, sub string) bool {
+	rest := []rune(strings.ReplaceAll(sub, " ", ""))
+	for _, r := range s {
+		if len(rest) > 0 && r == rest[0] {
+			rest = rest[1:]
+		}
+	}
+	return len(rest) == 0
+}
+
+// ShortIDs returns the shortest prefix of at least MinPrefix characters
+// that tells each item's ID apart from the others.
+func ShortIDs(items []Item) map[uuid.UUID]string {
+	ids := make(map[uuid.UUID]string, len(items))
+	for _, it := range items {
+		id := it.ID.String()
+		n := MinPrefix
+		for _, other := range items {
+			o := other.ID.String()
+			if o == id {
+				continue
+			}
+			for n < len(id) && strings.HasPrefix(o, id[:n]) {
+				n++
+			}
+		}
+		ids[it.ID] = id[:n]
+	}
+	return ids
+}
@@ -0,0 +1,65 @@
+package todo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RolloverMode decides what happens to unfinished one-time tasks left on
+// past days.
+type RolloverMode string
+
+const (
+	RolloverAuto   RolloverMode = "auto"
+	RolloverPrompt RolloverMode = "prompt"
+	RolloverOff    RolloverMode = "off"
+)
+
+// ParseRolloverMode validates a mode name from the environment or config.
+func ParseRolloverMode(s string) (RolloverMode, error) {
+	switch mode := RolloverMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case RolloverAuto, RolloverPrompt, RolloverOff:
+		return mode, nil
+	}
+	return RolloverOff, fmt.Errorf("unknown rollover mode %q, expected auto, prompt or off", s)
+}
+
+// Overdue returns the one-time tasks scheduled before today that are not
+// done yet. Someday and recurring tasks never roll over.
+func (l List) Overdue(today time.Time) []Item {
+	var out []Item
+	for _, it := range l {
+		if it.needsRollover(today) {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+func (it Item) needsRollover(today time.Time) bool {
+	return !it.Done && !it.IsSomeday && !it.Trashed() && it.RecurrenceRule == nil && midnight(it.Date).Before(midnight(today))
+}
+
+// Rollover moves every overdue task to today. The first date a task was
+// scheduled on is kept in OriginalDate and Rollovers counts how many times
+// it slipped. It returns the moved tasks.
+func (l *List) Rollover(today time.Time) []Item {
+	var moved []Item
+	for i := range *l {
+		item := &(*l)[i]
+		if !item.needsRollover(today) {
+			continue
+		}
+
+		if item.OriginalDate == nil {
+			original := item.Date
+			item.OriginalDate = &original
+		}
+		item.Rollovers++
+		item.Date = midnight(today)
+
+		moved = append(moved, *item)
+	}
+	return moved
+}
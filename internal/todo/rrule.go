@@ -0,0 +1,383 @@
+package todo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RFC 5545 RRULE support. Only the subset that maps onto RecurrenceRule is
+// accepted, everything else is rejected instead of being silently dropped.
+
+var rruleFreqs = map[string]Frequency{
+	"DAILY":   Daily,
+	"WEEKLY":  Weekly,
+	"MONTHLY": Monthly,
+	"YEARLY":  Yearly,
+}
+
+var rruleDays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// ParseRRule converts an RRULE value such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE"
+// into a RecurrenceRule for a task starting on start, the DTSTART of the
+// rule. A leading "RRULE:" is allowed.
+func ParseRRule(s string, start time.Time) (RecurrenceRule, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 6 && strings.EqualFold(s[:6], "RRULE:") {
+		s = s[6:]
+	}
+
+	parts := map[string]string{}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return RecurrenceRule{}, fmt.Errorf("rrule: malformed part %q", part)
+		}
+		key = strings.ToUpper(strings.TrimSpace(key))
+		if _, dup := parts[key]; dup {
+			return RecurrenceRule{}, fmt.Errorf("rrule: %s given more than once", key)
+		}
+		parts[key] = strings.ToUpper(strings.TrimSpace(value))
+	}
+
+	rule := RecurrenceRule{Interval: 1}
+
+	freq, ok := parts["FREQ"]
+	if !ok {
+		return RecurrenceRule{}, fmt.Errorf("rrule: FREQ is required")
+	}
+	if rule.Freq, ok = rruleFreqs[freq]; !ok {
+		return RecurrenceRule{}, fmt.Errorf("rrule: FREQ=%s is not supported, use DAILY, WEEKLY, MONTHLY or YEARLY", freq)
+	}
+
+	for key, value := range parts {
+		var err error
+		switch key {
+		case "FREQ":
+		case "INTERVAL":
+			var n uint64
+			n, err = strconv.ParseUint(value, 10, 8)
+			if err != nil || n == 0 {
+				return RecurrenceRule{}, fmt.Errorf("rrule: INTERVAL must be between 1 and 255, got %s", value)
+			}
+			rule.Interval = uint8(n)
+		case "COUNT":
+			var n uint64
+			n, err = strconv.ParseUint(value, 10, 16)
+			if err != nil || n == 0 {
+				return RecurrenceRule{}, fmt.Errorf("rrule: COUNT must be between 1 and 65535, got %s", value)
+			}
+			rule.Count = uint16(n)
+		case "UNTIL":
+			var until time.Time
+			until, err = parseRRuleDate(value)
+			if err != nil {
+				return RecurrenceRule{}, err
+			}
+			rule.Until = &until
+		case "BYDAY", "BYMONTHDAY", "BYSETPOS", "BYMONTH":
+			// handled below, they depend on each other
+		case "WKST":
+			if _, ok := rruleDays[value]; !ok {
+				return RecurrenceRule{}, fmt.Errorf("rrule: WKST=%s is not a weekday", value)
+			}
+		default:
+			return RecurrenceRule{}, fmt.Errorf("rrule: %s is not supported", key)
+		}
+	}
+
+	if rule.Count > 0 && rule.Until != nil {
+		return RecurrenceRule{}, fmt.Errorf("rrule: COUNT and UNTIL must not be used together")
+	}
+
+	if parts["BYSETPOS"] != "" && parts["BYDAY"] == "" && parts["BYMONTHDAY"] == "" {
+		return RecurrenceRule{}, fmt.Errorf("rrule: BYSETPOS is only supported together with BYDAY or BYMONTHDAY")
+	}
+	if err := rule.parseByDay(parts["BYDAY"], parts["BYSETPOS"]); err != nil {
+		return RecurrenceRule{}, err
+	}
+	if err := rule.parseByMonthDay(parts["BYMONTHDAY"], parts["BYSETPOS"]); err != nil {
+		return RecurrenceRule{}, err
+	}
+	if err := rule.checkStart(start, parts); err != nil {
+		return RecurrenceRule{}, err
+	}
+	return rule, nil
+}
+
+// parseByMonthDay fills MonthDay from BYMONTHDAY. weektcli moves a day
+// after the 28th to the end of shorter months, where RRULE skips those
+// months; the RRULE for that is the last of the days from the 28th on, e.g.
+// BYMONTHDAY=28,29,30;BYSETPOS=-1 for the 30th.
+func (r *RecurrenceRule) parseByMonthDay(byMonthDay, bySetPos string) error {
+	if byMonthDay == "" {
+		return nil
+	}
+	if r.Freq != Monthly && r.Freq != Yearly {
+		return fmt.Errorf("rrule: BYMONTHDAY is only supported on MONTHLY and YEARLY rules")
+	}
+	if len(r.Weekdays) > 0 {
+		return fmt.Errorf("rrule: BYMONTHDAY can't be combined with BYDAY")
+	}
+
+	days := strings.Split(byMonthDay, ",")
+	last, err := strconv.Atoi(days[len(days)-1])
+	if err != nil || last < 1 || last > 31 {
+		return fmt.Errorf("rrule: malformed BYMONTHDAY %q", byMonthDay)
+	}
+	switch {
+	case len(days) == 1 && bySetPos == "" && last <= 28:
+		r.MonthDay = uint8(last)
+	case len(days) == 1 && bySetPos == "":
+		return fmt.Errorf("rrule: BYMONTHDAY=%d skips the months without that day, weektcli can only move it to their last day: BYMONTHDAY=%s;BYSETPOS=-1", last, lastMonthDays(last))
+	case last > 28 && byMonthDay == lastMonthDays(last) && bySetPos == "-1":
+		r.MonthDay = uint8(last)
+	default:
+		return fmt.Errorf("rrule: BYMONTHDAY=%s is not supported, use a single day between 1 and 28, or 28,29,30;BYSETPOS=-1 for the 30th or the last day of shorter months", byMonthDay)
+	}
+	return nil
+}
+
+// lastMonthDays lists the days from the 28th to day, for the BYMONTHDAY of
+// a day that moves to the end of shorter months.
+func lastMonthDays(day int) string {
+	days := []string{}
+	for d := 28; d <= day; d++ {
+		days = append(days, strconv.Itoa(d))
+	}
+	return strings.Join(days, ",")
+}
+
+// checkStart rejects the rules that mean something else in RRULE than in
+// weektcli for a task starting on start.
+func (r *RecurrenceRule) checkStart(start time.Time, parts map[string]string) error {
+	if r.Freq == Yearly {
+		// RRULE repeats BYDAY and BYMONTHDAY in every month of the year
+		// unless BYMONTH narrows it down; weektcli stays in the start month
+		month := start.Month()
+		if value, ok := parts["BYMONTH"]; ok {
+			if n, err := strconv.Atoi(value); err != nil || time.Month(n) != month {
+				return fmt.Errorf("rrule: BYMONTH=%s is not supported, yearly tasks repeat in the month they start in, BYMONTH=%d", value, month)
+			}
+		} else if parts["BYDAY"] != "" || parts["BYMONTHDAY"] != "" {
+			return fmt.Errorf("rrule: YEARLY rules with BYDAY or BYMONTHDAY need BYMONTH=%d, the month the task starts in", month)
+		}
+	} else if _, ok := parts["BYMONTH"]; ok {
+		return fmt.Errorf("rrule: BYMONTH is only supported on YEARLY rules")
+	}
+
+	if r.Nth == 0 && r.MonthDay == 0 && skipsShortMonths(r.Freq, start) {
+		if r.Freq == Yearly {
+			return fmt.Errorf("rrule: FREQ=YEARLY from Feb 29 skips the years without one, weektcli can only move it to Feb 28: BYMONTH=2;BYMONTHDAY=28,29;BYSETPOS=-1")
+		}
+		return fmt.Errorf("rrule: FREQ=MONTHLY from day %d skips the months without that day, weektcli can only move it to their last day: BYMONTHDAY=%s;BYSETPOS=-1",
+			start.Day(), lastMonthDays(start.Day()))
+	}
+
+	if r.Freq == Weekly && r.Interval > 1 {
+		wkst := time.Monday
+		if value, ok := parts["WKST"]; ok {
+			wkst = rruleDays[value]
+		}
+		if !r.weeksFrom(start, wkst) {
+			return fmt.Errorf("rrule: with INTERVAL=%d the weeks start on %s (WKST), weektcli counts them from the start date; start the task on a %s",
+				r.Interval, wkst, wkst)
+		}
+	}
+	return nil
+}
+
+// skipsShortMonths reports whether RRULE skips months on a monthly or
+// yearly rule that takes its day from start.
+func skipsShortMonths(freq Frequency, start time.Time) bool {
+	switch freq {
+	case Monthly:
+		return start.Day() > 28
+	case Yearly:
+		return start.Month() == time.February && start.Day() == 29
+	}
+	return false
+}
+
+// weeksFrom reports whether counting the weeks of a weekly rule from
+// weeks starting on wkst, as RRULE does, picks the same days as counting
+// them from start.
+func (r *RecurrenceRule) weeksFrom(start time.Time, wkst time.Weekday) bool {
+	weekdays := r.Weekdays
+	if len(weekdays) == 0 {
+		weekdays = []time.Weekday{start.Weekday()}
+	}
+	// every weekday of the rule has to come before the end of the start week
+	intoWeek := (int(start.Weekday()) - int(wkst) + 7) % 7
+	for _, wd := range weekdays {
+		if intoWeek+(int(wd)-int(start.Weekday())+7)%7 >= 7 {
+			return false
+		}
+	}
+	return true
+}
+
+// parseByDay fills Weekdays and Nth from BYDAY and an optional BYSETPOS.
+func (r *RecurrenceRule) parseByDay(byDay, bySetPos string) error {
+	if byDay == "" {
+		return nil
+	}
+
+	nth := 0
+	for i, entry := range strings.Split(byDay, ",") {
+		if len(entry) < 2 {
+			return fmt.Errorf("rrule: malformed BYDAY entry %q", entry)
+		}
+		wd, ok := rruleDays[entry[len(entry)-2:]]
+		if !ok {
+			return fmt.Errorf("rrule: malformed BYDAY entry %q", entry)
+		}
+
+		n := 0
+		if prefix := entry[:len(entry)-2]; prefix != "" {
+			var err error
+			if n, err = strconv.Atoi(prefix); err != nil || n == 0 {
+				return fmt.Errorf("rrule: malformed BYDAY entry %q", entry)
+			}
+		}
+		if i > 0 && n != nth {
+			return fmt.Errorf("rrule: BYDAY entries with different positions are not supported")
+		}
+		nth = n
+
+		r.Weekdays = append(r.Weekdays, wd)
+	}
+
+	if bySetPos != "" {
+		if nth != 0 {
+			return fmt.Errorf("rrule: BYSETPOS can't be combined with numbered BYDAY entries")
+		}
+		if len(r.Weekdays) != 1 {
+			return fmt.Errorf("rrule: BYSETPOS is only supported with a single BYDAY weekday")
+		}
+		n, err := strconv.Atoi(bySetPos)
+		if err != nil || n == 0 {
+			return fmt.Errorf("rrule: malformed BYSETPOS %q", bySetPos)
+		}
+		nth = n
+	}
+
+	switch r.Freq {
+	case Daily:
+		return fmt.Errorf("rrule: BYDAY is not supported on DAILY rules")
+	case Weekly:
+		if nth != 0 {
+			return fmt.Errorf("rrule: numbered BYDAY entries are not supported on WEEKLY rules")
+		}
+	case Monthly, Yearly:
+		if nth == 0 {
+			return fmt.Errorf("rrule: BYDAY on %s rules needs a position such as 2TU or -1FR", strings.ToUpper(r.Freq.String()))
+		}
+		if nth < -1 || nth > 5 {
+			return fmt.Errorf("rrule: BYDAY position %d is not supported, use 1 to 5 or -1", nth)
+		}
+		r.Nth = int8(nth)
+	}
+	return nil
+}
+
+// parseRRuleDate reads an UNTIL value, either a DATE or a DATE-TIME.
+func parseRRuleDate(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102", "20060102T150405Z", "20060102T150405"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("rrule: malformed UNTIL %q, expected YYYYMMDD", value)
+}
+
+// RRule serializes the rule of a task starting on start as an RFC 5545
+// RRULE value. Skipped and changed occurrences belong to EXDATE and
+// RECURRENCE-ID and are not included.
+func (r RecurrenceRule) RRule(start time.Time) (string, error) {
+	if err := r.Validate(); err != nil {
+		return "", err
+	}
+	if r.Freq == None {
+		return "", fmt.Errorf("rrule: task does not repeat")
+	}
+	if r.AfterCompletion {
+		return "", fmt.Errorf("rrule: repeating after completion has no RRULE equivalent")
+	}
+
+	var parts []string
+	for name, freq := range rruleFreqs {
+		if freq == r.Freq {
+			parts = append(parts, "FREQ="+name)
+		}
+	}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+
+	monthDay := int(r.MonthDay)
+	if r.Nth == 0 && monthDay == 0 && skipsShortMonths(r.Freq, start) {
+		monthDay = start.Day()
+	}
+	if r.Freq == Yearly && (r.Nth != 0 || monthDay > 0) {
+		parts = append(parts, fmt.Sprintf("BYMONTH=%d", start.Month()))
+	}
+
+	weekdays := append([]time.Weekday{}, r.Weekdays...)
+	if r.Nth != 0 && len(weekdays) == 0 {
+		// The nth weekday without weekdays falls on the start's weekday
+		weekdays = []time.Weekday{start.Weekday()}
+	}
+	usesWeekdays := r.Freq == Weekly || ((r.Freq == Monthly || r.Freq == Yearly) && r.Nth != 0)
+	if usesWeekdays && len(weekdays) > 0 {
+		// Monday first, the way calendars list them
+		sort.Slice(weekdays, func(i, j int) bool {
+			return (weekdays[i]+6)%7 < (weekdays[j]+6)%7
+		})
+
+		prefix := ""
+		if r.Nth != 0 && r.Freq != Weekly {
+			prefix = strconv.Itoa(int(r.Nth))
+		}
+
+		var days []string
+		for _, wd := range weekdays {
+			days = append(days, prefix+strings.ToUpper(wd.String()[:2]))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+
+	if (r.Freq == Monthly || r.Freq == Yearly) && r.Nth == 0 {
+		if monthDay > 28 {
+			parts = append(parts, "BYMONTHDAY="+lastMonthDays(monthDay), "BYSETPOS=-1")
+		} else if monthDay > 0 {
+			parts = append(parts, fmt.Sprintf("BYMONTHDAY=%d", monthDay))
+		}
+	}
+
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	// weektcli counts the weeks from the start date
+	if r.Freq == Weekly && r.Interval > 1 && !r.weeksFrom(start, time.Monday) {
+		parts = append(parts, "WKST="+strings.ToUpper(start.Weekday().String()[:2]))
+	}
+
+	return strings.Join(parts, ";"), nil
+}
@@ -0,0 +1,76 @@
+package todo
+
+import (
+	"testing"
+	"time"
+)
+
+// day parses a YYYY-MM-DD date the way the tasks store them.
+func day(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.ParseInLocation(DateLayout, s, time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestRRuleRoundTrip(t *testing.T) {
+	tests := []struct {
+		rrule string
+		start string
+	}{
+		{"FREQ=DAILY", "2026-10-16"},
+		{"FREQ=DAILY;INTERVAL=3;COUNT=5", "2026-10-16"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", "2026-10-12"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;WKST=TH", "2026-10-15"},
+		{"FREQ=WEEKLY;BYDAY=FR;UNTIL=20261231", "2026-10-16"},
+		{"FREQ=MONTHLY;BYMONTHDAY=15", "2026-10-15"},
+		{"FREQ=MONTHLY;BYMONTHDAY=28,29,30,31;BYSETPOS=-1", "2026-10-31"},
+		{"FREQ=MONTHLY;BYDAY=2TU", "2026-10-13"},
+		{"FREQ=MONTHLY;BYDAY=-1FR;COUNT=3", "2026-10-30"},
+		{"FREQ=YEARLY;BYMONTH=10;BYDAY=2TU", "2026-10-13"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rrule, func(t *testing.T) {
+			start := day(t, tt.start)
+			rule, err := ParseRRule(tt.rrule, start)
+			if err != nil {
+				t.Fatalf("ParseRRule: %v", err)
+			}
+			got, err := rule.RRule(start)
+			if err != nil {
+				t.Fatalf("RRule: %v", err)
+			}
+			if got != tt.rrule {
+				t.Errorf("RRule() = %q, want %q", got, tt.rrule)
+			}
+		})
+	}
+}
+
+func TestRRuleNthWithoutWeekdays(t *testing.T) {
+	rule := RecurrenceRule{Freq: Monthly, Interval: 1, Nth: 2}
+	got, err := rule.RRule(day(t, "2026-10-13"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "FREQ=MONTHLY;BYDAY=2TU"; got != want {
+		t.Errorf("RRule() = %q, want %q", got, want)
+	}
+}
+
+func TestParseRRuleRejects(t *testing.T) {
+	tests := []string{
+		"FREQ=HOURLY",
+		"FREQ=DAILY;COUNT=3;UNTIL=20261231",
+		"FREQ=MONTHLY;BYMONTHDAY=-1",
+		"FREQ=YEARLY;BYMONTH=3",
+		"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SA;WKST=WE",
+	}
+	for _, rrule := range tests {
+		if _, err := ParseRRule(rrule, day(t, "2026-10-12")); err == nil {
+			t.Errorf("ParseRRule(%q) succeeded, want an error", rrule)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package todo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// SchemaVersion is the data file format Save writes. Version 1 is the
+// original bare JSON array of items, every later version wraps the items
+// in an envelope: {"version": N, "items": [...]}.
+const SchemaVersion = 2
+
+// migrations upgrade a data file one version at a time, in order.
+var migrations = []migration{
+	{to: 2, description: "wrap the task list in a versioned envelope"},
+}
+
+type migration struct {
+	to          int
+	description string
+
+	// item upgrades a single item from the previous version. It is nil
+	// when only the layout of the file changed.
+	item func(item map[string]any) error
+}
+
+// Migration reports one upgrade step applied to a data file.
+type Migration struct {
+	Version     int
+	Description string
+	// Changed counts the items the step rewrote.
+	Changed int
+}
+
+// ErrNewerSchema is returned for data files written by a newer weektcli.
+var ErrNewerSchema = errors.New("data file was written by a newer weektcli, please upgrade")
+
+type envelope struct {
+	Version int             `json:"version"`
+	Items   json.RawMessage `json:"items"`
+}
+
+// fileVersion tells the versions of the data file apart and returns the raw
+// item array.
+func fileVersion(data []byte) (int, json.RawMessage, error) {
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) || bytes.Equal(data, []byte("null")) {
+		return 1, data, nil
+	}
+
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return 0, nil, err
+	}
+	if env.Version < 2 {
+		return 0, nil, fmt.Errorf("data file has no valid version (got %d)", env.Version)
+	}
+	if env.Version > SchemaVersion {
+		return 0, nil, fmt.Errorf("%w (file version %d, supported up to %d)", ErrNewerSchema, env.Version, SchemaVersion)
+	}
+	return env.Version, env.Items, nil
+}
+
+// upgrade brings data in any known version up to SchemaVersion and returns
+// the item array along with the steps it took.
+func upgrade(data []byte) (json.RawMessage, []Migration, error) {
+	version, raw, err := fileVersion(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version == SchemaVersion {
+		return raw, nil, nil
+	}
+
+	var items []map[string]any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber() // keep numbers exactly as written
+	if err := dec.Decode(&items); err != nil {
+		return nil, nil, err
+	}
+
+	var applied []Migration
+	for _, m := range migrations {
+		if m.to <= version {
+			continue
+		}
+		step := Migration{Version: m.to, Description: m.description}
+		if m.item != nil {
+			for _, item := range items {
+				before, _ := json.Marshal(item)
+				if err := m.item(item); err != nil {
+					return nil, nil, fmt.Errorf("migrate to version %d: %w", m.to, err)
+				}
+				if after, _ := json.Marshal(item); !bytes.Equal(before, after) {
+					step.Changed++
+				}
+			}
+		}
+		applied = append(applied, step)
+	}
+
+	raw, err = json.Marshal(items)
+	return raw, applied, err
+}
+
+// PlanMigration reports the version of filename and the migrations Load
+// would apply to it, without changing anything.
+func PlanMigration(filename string) (int, []Migration, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return 0, nil, err
+	}
+	version, _, err := fileVersion(data)
+	if err != nil {
+		return 0, nil, err
+	}
+	_, steps, err := upgrade(data)
+	return version, steps, err
+}
+
+// MigrateFile upgrades filename to SchemaVersion in place. The old version
+// is kept as the backup.
+func MigrateFile(filename string) ([]Migration, error) {
+	unlock, err := Lock(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	_, steps, err := upgrade(data)
+	if err != nil || len(steps) == 0 {
+		return nil, err
+	}
+
+	var l List
+	if err := l.decode(data); err != nil {
+		return nil, err
+	}
+	return steps, l.Save(filename)
+}
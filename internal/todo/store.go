@@ -0,0 +1,94 @@
+package todo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Store is where a List lives between runs.
+type Store interface {
+	// Load returns every item, in the order they were added.
+	Load() (List, error)
+	// Save replaces the stored list with l.
+	Save(l List) error
+	// Upsert adds the items, or replaces stored items with the same ID.
+	Upsert(items ...Item) error
+	// Delete removes the items with the given IDs. Unknown IDs are ignored.
+	Delete(ids ...uuid.UUID) error
+	// Window returns the items that can show up in [from, to): one-time
+	// tasks dated in that range, recurring tasks started before to and the
+	// Someday tasks. Trashed items are left out.
+	Window(from, to time.Time) (List, error)
+	// Path is the file backing the store, to watch it for changes.
+	Path() string
+	Close() error
+}
+
+// StoreKinds lists the backends OpenStore knows.
+var StoreKinds = []string{"json", "sqlite"}
+
+// ParseStoreKind validates a backend name for OpenStore.
+func ParseStoreKind(kind string) (string, error) {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	for _, k := range StoreKinds {
+		if kind == k {
+			return kind, nil
+		}
+	}
+	return "", fmt.Errorf("unknown store %q, expected one of %s", kind, strings.Join(StoreKinds, ", "))
+}
+
+// OpenStore opens the backend kind ("json" or "sqlite") at path.
+func OpenStore(kind, path string) (Store, error) {
+	kind, err := ParseStoreKind(kind)
+	if err != nil {
+		return nil, err
+	}
+	if kind == "sqlite" {
+		return OpenSQLiteStore(path)
+	}
+	return NewJSONStore(path), nil
+}
+
+// SaveChanges writes what changed between base and local to s, one item
+// at a time, so changes other processes stored in the meantime are kept.
+func SaveChanges(s Store, base, local List) error {
+	changed, removed := Diff(base, local)
+
+	if len(changed) > 0 {
+		byID := make(map[uuid.UUID]Item, len(local))
+		for _, item := range local {
+			byID[item.ID] = item
+		}
+		items := make([]Item, 0, len(changed))
+		for _, id := range changed {
+			items = append(items, byID[id])
+		}
+		if err := s.Upsert(items...); err != nil {
+			return err
+		}
+	}
+
+	if len(removed) > 0 {
+		return s.Delete(removed...)
+	}
+	return nil
+}
+
+// inWindow reports whether the item can occur in [from, to), see Store.Window.
+func (it Item) inWindow(from, to time.Time) bool {
+	if it.Trashed() {
+		return false
+	}
+	if it.IsSomeday {
+		return true
+	}
+	day, from, to := midnight(it.Date), midnight(from), midnight(to)
+	if it.RecurrenceRule != nil && it.RecurrenceRule.Freq != None {
+		return day.Before(to)
+	}
+	return !day.Before(from) && day.Before(to)
+}
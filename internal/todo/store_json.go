@@ -0,0 +1,86 @@
+package todo
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JSONStore keeps the whole list in one JSON file. Every write rewrites the
+// file under the file lock.
+type JSONStore struct {
+	filename string
+}
+
+func NewJSONStore(filename string) *JSONStore {
+	return &JSONStore{filename: filename}
+}
+
+func (s *JSONStore) Path() string { return s.filename }
+
+func (s *JSONStore) Close() error { return nil }
+
+func (s *JSONStore) Load() (List, error) {
+	var l List
+	err := l.Load(s.filename)
+	return l, err
+}
+
+func (s *JSONStore) Save(l List) error {
+	unlock, err := Lock(s.filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return l.Save(s.filename)
+}
+
+func (s *JSONStore) Upsert(items ...Item) error {
+	return s.update(func(l *List) {
+		for _, item := range items {
+			if existing := l.find(item.ID); existing != nil {
+				*existing = item
+			} else {
+				*l = append(*l, item)
+			}
+		}
+	})
+}
+
+func (s *JSONStore) Delete(ids ...uuid.UUID) error {
+	return s.update(func(l *List) {
+		for _, id := range ids {
+			l.remove(id)
+		}
+	})
+}
+
+func (s *JSONStore) Window(from, to time.Time) (List, error) {
+	// the whole file has to be read anyway; errors are those of Load
+	all, err := s.Load()
+	var out List
+	for _, item := range all {
+		if item.inWindow(from, to) {
+			out = append(out, item)
+		}
+	}
+	return out, err
+}
+
+// update applies fn to the current file contents while holding the lock.
+func (s *JSONStore) update(fn func(*List)) error {
+	unlock, err := Lock(s.filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var l List
+	if err := l.Load(s.filename); err != nil && !errors.Is(err, os.ErrNotExist) && !errors.Is(err, ErrRestoredBackup) {
+		return err
+	}
+	fn(&l)
+	return l.Save(s.filename)
+}
@@ -0,0 +1,154 @@
+package todo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStore keeps one row per item, so a change only writes that item.
+// Each row holds the item as JSON next to the columns needed for queries.
+type SQLiteStore struct {
+	db   *sql.DB
+	path string
+}
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS items (
+	id        TEXT PRIMARY KEY,
+	position  INTEGER NOT NULL,
+	day       TEXT NOT NULL,
+	someday   INTEGER NOT NULL,
+	recurring INTEGER NOT NULL,
+	data      TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS items_day ON items (day);
+CREATE INDEX IF NOT EXISTS items_recurring ON items (recurring, day);
+CREATE INDEX IF NOT EXISTS items_someday ON items (someday);
+`
+
+// OpenSQLiteStore opens or creates the database at path.
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	// wait for other weektcli processes instead of failing with SQLITE_BUSY
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteStore{db: db, path: path}, nil
+}
+
+func (s *SQLiteStore) Path() string { return s.path }
+
+func (s *SQLiteStore) Close() error { return s.db.Close() }
+
+func (s *SQLiteStore) Load() (List, error) {
+	return s.query(`SELECT data FROM items ORDER BY position`)
+}
+
+func (s *SQLiteStore) Window(from, to time.Time) (List, error) {
+	start, end := from.Format(DateLayout), to.Format(DateLayout)
+	return s.query(`SELECT data FROM items
+		WHERE json_extract(data, '$.deleted_at') IS NULL AND (
+			someday = 1 OR
+			(recurring = 0 AND someday = 0 AND day >= ? AND day < ?) OR
+			(recurring = 1 AND day < ?))
+		ORDER BY position`, start, end, end)
+}
+
+func (s *SQLiteStore) query(q string, args ...any) (List, error) {
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var l List
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var item Item
+		if err := json.Unmarshal(data, &item); err != nil {
+			return nil, err
+		}
+		l = append(l, item)
+	}
+	return l, rows.Err()
+}
+
+func (s *SQLiteStore) Save(l List) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM items`); err != nil {
+			return err
+		}
+		for i, item := range l {
+			if err := upsertItem(tx, item, i+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteStore) Upsert(items ...Item) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		var last int
+		if err := tx.QueryRow(`SELECT COALESCE(MAX(position), 0) FROM items`).Scan(&last); err != nil {
+			return err
+		}
+		for i, item := range items {
+			if err := upsertItem(tx, item, last+i+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteStore) Delete(ids ...uuid.UUID) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		for _, id := range ids {
+			if _, err := tx.Exec(`DELETE FROM items WHERE id = ?`, id.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// upsertItem writes one row. position is only used for new items, existing
+// ones keep their place in the list.
+func upsertItem(tx *sql.Tx, item Item, position int) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	recurring := item.RecurrenceRule != nil && item.RecurrenceRule.Freq != None
+	_, err = tx.Exec(`INSERT INTO items (id, position, day, someday, recurring, data)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			day = excluded.day, someday = excluded.someday,
+			recurring = excluded.recurring, data = excluded.data`,
+		item.ID.String(), position, item.Date.Format(DateLayout), item.IsSomeday, recurring, data)
+	return err
+}
+
+func (s *SQLiteStore) inTx(fn func(*sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -2,12 +2,14 @@ package todo
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"time"
 
 	"github.com/google/uuid"
 )
+
 type Frequency uint8
 
 const (
@@ -15,75 +17,195 @@ const (
 	Daily
 	Weekly
 	Monthly
+	Yearly
+)
+
+// Priority ranks tasks, the zero value has none.
+type Priority uint8
+
+const (
+	NoPriority Priority = iota
+	Low
+	Medium
+	High
 )
 
 type RecurrenceRule struct {
-	Freq      Frequency   `json:"freq"`
-	Interval  uint8       `json:"interval"`
-	Weekdays  []time.Weekday `json:"weekdays"`
-	MonthDay  uint8       `json:"month_day"`
-	DoneList  []string    `json:"done_list"`
+	Freq     Frequency      `json:"freq"`
+	Interval uint8          `json:"interval"`
+	Weekdays []time.Weekday `json:"weekdays"`
+	MonthDay uint8          `json:"month_day"`
+	DoneList []string       `json:"done_list"`
+
+	// Nth picks the nth weekday of the month (1-5, -1 for the last one) for
+	// monthly and yearly rules, e.g. "2nd Tuesday". The weekdays come from
+	// Weekdays, or the weekday of the start date when that is empty.
+	Nth int8 `json:"nth,omitempty"`
+
+	// AfterCompletion schedules the next occurrence Interval days, weeks,
+	// months or years after the last entry in DoneList instead of on a
+	// fixed calendar. Weekdays, MonthDay and Nth are ignored in this mode.
+	AfterCompletion bool `json:"after_completion,omitempty"`
+
+	// End conditions, both optional. Until is the last day (inclusive) the
+	// task may occur on, Count caps the total number of occurrences.
+	Until *time.Time `json:"until,omitempty"`
+	Count uint16     `json:"count,omitempty"`
+
+	// Exceptions holds skipped days (YYYY-MM-DD), like EXDATE in iCalendar.
+	// Skipped days still count towards Count.
+	Exceptions []string `json:"exceptions,omitempty"`
+
+	// Overrides changes single occurrences, keyed by the day (YYYY-MM-DD)
+	// the occurrence was originally scheduled on.
+	Overrides map[string]Override `json:"overrides,omitempty"`
+
+	// ArchivedRuns lists the checked-off occurrences that were moved to the
+	// archive, as runs "YYYY-MM-DD/YYYY-MM-DD" from the first to the last
+	// day of occurrences in a row. A missed occurrence ends a run, so it
+	// stays in sight. The series no longer shows up on archived days.
+	ArchivedRuns []string `json:"archived_runs,omitempty"`
+	// ArchivedBefore is the day (YYYY-MM-DD) before which every occurrence
+	// was archived, as older versions recorded it.
+	ArchivedBefore string `json:"archived_before,omitempty"`
+	// Archived counts the archived completions of rules that repeat after
+	// completion, which have no fixed days to record.
+	Archived uint16 `json:"archived,omitempty"`
+}
+
+// Override replaces fields of one occurrence of a recurring task, like
+// RECURRENCE-ID in iCalendar. Nil fields keep the value of the series.
+type Override struct {
+	Task  *string    `json:"task,omitempty"`
+	Notes *string    `json:"notes,omitempty"`
+	Date  *time.Time `json:"date,omitempty"`
 }
 
 type Item struct {
-	ID        uuid.UUID `json:"id"`
-	Task      string    `json:"task"`
-	Notes     string    `json:"notes"`
-	Done      bool      `json:"done"`
-	Date      time.Time `json:"date"`
-	IsSomeday bool      `json:"is_someday"`
+	ID    uuid.UUID `json:"id"`
+	Task  string    `json:"task"`
+	Notes string    `json:"notes"`
+	Done  bool      `json:"done"`
+	// CompletedAt is when a one-time task was checked off.
+	CompletedAt    *time.Time      `json:"completed_at,omitempty"`
+	Date           time.Time       `json:"date"`
+	IsSomeday      bool            `json:"is_someday"`
 	RecurrenceRule *RecurrenceRule `json:"recurrence_rule,omitempty"`
+
+	Priority Priority `json:"priority,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+
+	// Set when an unfinished task rolls over to a later day: the date it was
+	// first scheduled on and how many times it slipped since.
+	OriginalDate *time.Time `json:"original_date,omitempty"`
+	Rollovers    uint16     `json:"rollovers,omitempty"`
+
+	// DeletedAt is set while the item is in the trash. Trashed items are
+	// hidden everywhere but the trash until restored or purged.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 type List []Item
 
-func (l *List) Add(task,note string, date time.Time, someday bool) Item {
+func (l *List) Add(task, note string, date time.Time, someday bool) Item {
 	item := Item{
-		ID:        uuid.New(),
-		Task:      task,
-		Done:      false,
-		Date:      date,
-		Notes:     note,
-		IsSomeday: someday,
+		ID:             uuid.New(),
+		Task:           task,
+		Done:           false,
+		Date:           date,
+		Notes:          note,
+		IsSomeday:      someday,
 		RecurrenceRule: nil,
 	}
 	*l = append(*l, item)
 	return item
 }
 
-func (l *List) DeleteTask(filename, taskId string) error {
-	ls := *l
-	found := false
+// DeleteTask moves a task to the trash, see Restore and Purge.
+func (l *List) DeleteTask(taskId string) error {
+	id, err := uuid.Parse(taskId)
+	item := l.find(id)
+	if err != nil || item == nil || item.Trashed() {
+		return fmt.Errorf("task with ID %s not found", taskId)
+	}
+	now := time.Now()
+	item.DeletedAt = &now
+	return nil
+}
 
+func (l *List) remove(id uuid.UUID) bool {
+	ls := *l
 	for i, item := range ls {
-		// Compare the string version of the UUID
-		if item.ID.String() == taskId {
+		if item.ID == id {
 			// Remove the item by joining everything before it and everything after it
 			*l = append(ls[:i], ls[i+1:]...)
-			found = true
-			break
+			return true
 		}
 	}
+	return false
+}
 
-	if !found {
-		return fmt.Errorf("task with ID %s not found", taskId)
+// find returns the item with the given ID, or nil.
+func (l *List) find(id uuid.UUID) *Item {
+	for i := range *l {
+		if (*l)[i].ID == id {
+			return &(*l)[i]
+		}
 	}
-
-	// Save the changes to the JSON file
-	return l.Save(filename)
+	return nil
 }
 
+// Save writes the list to filename atomically and keeps the previous
+// version of the file next to it as a backup.
 func (l *List) Save(filename string) error {
-	data, _ := json.Marshal(l)
-	return os.WriteFile(filename, data, 0644)
+	items, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(envelope{Version: SchemaVersion, Items: items})
+	if err != nil {
+		return err
+	}
+	if err := backup(filename); err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, data, 0644)
 }
 
+// Load reads the list from filename, upgrading older file versions in
+// memory; the next Save writes the current version. If the file is unreadable or corrupt
+// the backup is loaded instead and ErrRestoredBackup is returned alongside
+// it, so callers can warn before the next Save replaces the broken file.
 func (l *List) Load(filename string) error {
 	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		if err = l.decode(data); err == nil || errors.Is(err, ErrNewerSchema) {
+			// a newer file is fine, just not ours to touch
+			return err
+		}
+	}
+
+	bak, bakErr := os.ReadFile(BackupName(filename))
+	if bakErr != nil || l.decode(bak) != nil {
+		return fmt.Errorf("load %s: %w", filename, err)
+	}
+	return fmt.Errorf("%w (%v)", ErrRestoredBackup, err)
+}
+
+func (l *List) decode(data []byte) error {
+	raw, _, err := upgrade(data)
 	if err != nil {
 		return err
 	}
-	return json.Unmarshal(data, l)
+	var items List
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return err
+	}
+	*l = items
+	return nil
 }
 
 func (l *List) GetTaskDetails(taskId string) (Item, error) {
@@ -106,24 +228,71 @@ func (l *List) UpdateTask(id uuid.UUID, newTaskName, newNotes string) {
 	}
 }
 
-func (l *List) MoveTask(id uuid.UUID, newDate time.Time)  {
-	for i := range *l {
-		if (*l)[i].ID == id {
-			(*l)[i].Date = newDate
-			return
-		}
+// MoveTask reschedules the whole task to newDate, keeping the weekday or
+// day of month of recurring tasks in line with the new date.
+func (l *List) MoveTask(id uuid.UUID, newDate time.Time) {
+	item := l.find(id)
+	if item == nil {
+		return
 	}
-}
 
-func (l *List) UpdateRecurrenceRule(id uuid.UUID, rule RecurrenceRule) {
-	for i := range *l {
-		if (*l)[i].ID == id {
-			if rule.Freq == None {
-				(*l)[i].RecurrenceRule = nil
-			} else {
-				(*l)[i].RecurrenceRule = &rule
+	item.Date = newDate
+	item.IsSomeday = false
+
+	if item.RecurrenceRule != nil {
+		switch item.RecurrenceRule.Freq {
+
+		case Weekly:
+			item.RecurrenceRule.Weekdays = []time.Weekday{newDate.Weekday()}
+
+		case Monthly, Yearly:
+			if item.RecurrenceRule.Nth == 0 {
+				item.RecurrenceRule.MonthDay = uint8(newDate.Day())
+				break
+			}
+
+			// Keep "last ..." rules on the last weekday when possible
+			item.RecurrenceRule.Weekdays = []time.Weekday{newDate.Weekday()}
+			if item.RecurrenceRule.Nth > 0 || !IsLastWeekdayOfMonth(newDate) {
+				item.RecurrenceRule.Nth = int8(NthWeekdayOfMonth(newDate))
 			}
-			return
 		}
 	}
-}
\ No newline at end of file
+}
+
+// MoveToSomeday takes the task out of the calendar. Recurring tasks need a
+// date, so they can't go to Someday.
+func (l *List) MoveToSomeday(id uuid.UUID) error {
+	item := l.find(id)
+	if item == nil {
+		return fmt.Errorf("task with ID %s not found", id)
+	}
+	if item.RecurrenceRule != nil {
+		return errors.New("Someday tasks can't repeat")
+	}
+	item.IsSomeday = true
+	item.Date = time.Time{}
+	return nil
+}
+
+// UpdateRecurrenceRule sets how a task repeats, or stops it repeating for
+// the None frequency. Someday tasks have no day to repeat from and are
+// refused.
+func (l *List) UpdateRecurrenceRule(id uuid.UUID, rule RecurrenceRule) error {
+	item := l.find(id)
+	if item == nil {
+		return fmt.Errorf("task with ID %s not found", id)
+	}
+	if rule.Freq == None {
+		item.RecurrenceRule = nil
+		return nil
+	}
+	if item.IsSomeday {
+		return errors.New("Someday tasks can't repeat, give the task a day first")
+	}
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	item.RecurrenceRule = &rule
+	return nil
+}
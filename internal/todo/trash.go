@@ -0,0 +1,75 @@
+package todo
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Trashed reports whether the item was deleted and waits in the trash.
+func (it Item) Trashed() bool {
+	return it.DeletedAt != nil
+}
+
+// Active returns the items that are not in the trash.
+func (l List) Active() []Item {
+	var out []Item
+	for _, it := range l {
+		if !it.Trashed() {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// Trash returns the deleted items, most recently deleted first.
+func (l List) Trash() []Item {
+	var out []Item
+	for _, it := range l {
+		if it.Trashed() {
+			out = append(out, it)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].DeletedAt.After(*out[j].DeletedAt)
+	})
+	return out
+}
+
+// Restore takes an item out of the trash.
+func (l *List) Restore(id uuid.UUID) error {
+	item := l.find(id)
+	if item == nil || !item.Trashed() {
+		return fmt.Errorf("task with ID %s is not in the trash", id)
+	}
+	item.DeletedAt = nil
+	return nil
+}
+
+// Purge removes an item from the trash for good.
+func (l *List) Purge(id uuid.UUID) error {
+	item := l.find(id)
+	if item == nil || !item.Trashed() {
+		return fmt.Errorf("task with ID %s is not in the trash", id)
+	}
+	l.remove(id)
+	return nil
+}
+
+// PurgeTrash removes every item deleted before cutoff for good and returns
+// them. A zero cutoff empties the whole trash.
+func (l *List) PurgeTrash(cutoff time.Time) []Item {
+	var purged []Item
+	kept := (*l)[:0]
+	for _, it := range *l {
+		if it.Trashed() && (cutoff.IsZero() || it.DeletedAt.Before(cutoff)) {
+			purged = append(purged, it)
+			continue
+		}
+		kept = append(kept, it)
+	}
+	*l = kept
+	return purged
+}
@@ -0,0 +1,118 @@
+package todo
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// PollInterval is how often a Watcher checks the data file when the
+// platform has no file notifications.
+var PollInterval = time.Second
+
+// Watcher reports changes to a data file made by any process, including
+// this one. Several changes in a row are folded into a single signal.
+type Watcher struct {
+	changes chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	fsw     *fsnotify.Watcher
+}
+
+// Watch starts watching filename. It uses inotify (or the platform's
+// equivalent) and falls back to polling the file's size and modification
+// time when that is not available.
+func Watch(filename string) *Watcher {
+	w := &Watcher{
+		changes: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+
+	// Saves rename a temp file over the data file, so watch the directory:
+	// a watch on the file itself would stay on the replaced inode.
+	fsw, err := fsnotify.NewWatcher()
+	if err == nil {
+		if err = fsw.Add(filepath.Dir(filename)); err == nil {
+			w.fsw = fsw
+			go w.notify(fsw, filepath.Clean(filename))
+			return w
+		}
+		fsw.Close()
+	}
+
+	go w.poll(filename)
+	return w
+}
+
+// Changes delivers a value whenever the file changed since the last read.
+func (w *Watcher) Changes() <-chan struct{} {
+	return w.changes
+}
+
+// Close stops watching.
+func (w *Watcher) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+		if w.fsw != nil {
+			w.fsw.Close()
+		}
+	})
+	return nil
+}
+
+func (w *Watcher) signal() {
+	select {
+	case w.changes <- struct{}{}:
+	default: // a change is already pending
+	}
+}
+
+func (w *Watcher) notify(fsw *fsnotify.Watcher, filename string) {
+	for {
+		select {
+		case <-w.done:
+			return
+		case ev, ok := <-fsw.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(ev.Name) == filename && !ev.Has(fsnotify.Chmod) {
+				w.signal()
+			}
+		case _, ok := <-fsw.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+func (w *Watcher) poll(filename string) {
+	stamp := func() (time.Time, int64) {
+		fi, err := os.Stat(filename)
+		if err != nil {
+			return time.Time{}, -1
+		}
+		return fi.ModTime(), fi.Size()
+	}
+
+	lastMod, lastSize := stamp()
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			mod, size := stamp()
+			if !mod.Equal(lastMod) || size != lastSize {
+				lastMod, lastSize = mod, size
+				w.signal()
+			}
+		}
+	}
+}
@@ -0,0 +1,460 @@
+package tui
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// KeyMap holds the key bindings of every mode. Each mode is a struct of
+// key.Bindings; Remap addresses them as <mode>.<action>, with both names in
+// snake_case, e.g. grid.toggle or calendar.next_month.
+type KeyMap struct {
+	Grid       GridKeys
+	Form       FormKeys
+	Details    DetailsKeys
+	Move       MoveKeys
+	Calendar   CalendarKeys
+	Recurrence RecurrenceKeys
+	Delete     DeleteKeys
+	Scope      ScopeKeys
+	Trash      TrashKeys
+	Logbook    LogbookKeys
+	Rollover   RolloverKeys
+	Help       HelpKeys
+}
+
+// GridKeys work on the week view.
+type GridKeys struct {
+	Left       key.Binding
+	Right      key.Binding
+	Up         key.Binding
+	Down       key.Binding
+	PrevWeek   key.Binding
+	NextWeek   key.Binding
+	Toggle     key.Binding
+	New        key.Binding
+	Edit       key.Binding
+	Move       key.Binding
+	Recurrence key.Binding
+	Delete     key.Binding
+	Details    key.Binding
+	Undo       key.Binding
+	Redo       key.Binding
+	Trash      key.Binding
+	Logbook    key.Binding
+	Help       key.Binding
+	Quit       key.Binding
+}
+
+// FormKeys work in the new task and edit task dialogs.
+type FormKeys struct {
+	NextField key.Binding
+	Submit    key.Binding // saves from the title field, Enter adds a line in the notes
+	Save      key.Binding
+	Cancel    key.Binding
+}
+
+type DetailsKeys struct {
+	Edit  key.Binding
+	Close key.Binding
+}
+
+type MoveKeys struct {
+	Someday  key.Binding
+	Today    key.Binding
+	Calendar key.Binding
+	Cancel   key.Binding
+}
+
+type CalendarKeys struct {
+	Left      key.Binding
+	Right     key.Binding
+	Up        key.Binding
+	Down      key.Binding
+	PrevMonth key.Binding
+	NextMonth key.Binding
+	PrevYear  key.Binding
+	NextYear  key.Binding
+	Jump      key.Binding // types a date like "next fri", confirmed with the form keys
+	Pick      key.Binding
+	Cancel    key.Binding
+}
+
+type RecurrenceKeys struct {
+	NextRow   key.Binding
+	PrevRow   key.Binding
+	Left      key.Binding
+	Right     key.Binding
+	Up        key.Binding
+	Down      key.Binding
+	ToggleDay key.Binding
+	Save      key.Binding
+	Cancel    key.Binding
+}
+
+// DeleteKeys answer the delete confirmation. One-time tasks only use
+// Occurrence, which deletes the task.
+type DeleteKeys struct {
+	Occurrence key.Binding
+	Following  key.Binding
+	Series     key.Binding
+	Cancel     key.Binding
+}
+
+type ScopeKeys struct {
+	Occurrence key.Binding
+	Series     key.Binding
+	Cancel     key.Binding
+}
+
+type TrashKeys struct {
+	Up      key.Binding
+	Down    key.Binding
+	Restore key.Binding
+	Purge   key.Binding
+	Empty   key.Binding
+	Close   key.Binding
+}
+
+type LogbookKeys struct {
+	Up    key.Binding
+	Down  key.Binding
+	Close key.Binding
+}
+
+type RolloverKeys struct {
+	Yes key.Binding
+	No  key.Binding
+}
+
+type HelpKeys struct {
+	Close key.Binding
+}
+
+// DefaultKeyMap is the built-in layout.
+func DefaultKeyMap() KeyMap {
+	b := func(help string, keys ...string) key.Binding {
+		return key.NewBinding(key.WithKeys(keys...), key.WithHelp(keyLabel(keys), help))
+	}
+	return KeyMap{
+		Grid: GridKeys{
+			Left:       b("day", "left", "h"),
+			Right:      b("day", "right", "l"),
+			Up:         b("task", "up", "k"),
+			Down:       b("task", "down", "j"),
+			PrevWeek:   b("prev week", "["),
+			NextWeek:   b("next week", "]"),
+			Toggle:     b("toggle", " "),
+			New:        b("add task", "n"),
+			Edit:       b("edit", "e"),
+			Move:       b("move", "m"),
+			Recurrence: b("repeat", "r"),
+			Delete:     b("delete", "x", "delete", "backspace"),
+			Details:    b("details", "i", "enter"),
+			Undo:       b("undo", "u"),
+			Redo:       b("redo", "ctrl+r"),
+			Trash:      b("trash", "t"),
+			Logbook:    b("logbook", "L"),
+			Help:       b("help", "?"),
+			Quit:       b("quit", "ctrl+c", "q", "esc"),
+		},
+		Form: FormKeys{
+			NextField: b("next field", "tab"),
+			Submit:    b("save", "enter"),
+			Save:      b("save from notes", "ctrl+s"),
+			Cancel:    b("cancel", "esc"),
+		},
+		Details: DetailsKeys{
+			Edit:  b("edit", "e"),
+			Close: b("close", "q", "esc"),
+		},
+		Move: MoveKeys{
+			Someday:  b("someday", "s"),
+			Today:    b("today", "t"),
+			Calendar: b("pick a date", "c"),
+			Cancel:   b("cancel", "esc"),
+		},
+		Calendar: CalendarKeys{
+			Left:      b("day", "left", "h"),
+			Right:     b("day", "right", "l"),
+			Up:        b("week", "up", "k"),
+			Down:      b("week", "down", "j"),
+			PrevMonth: b("prev month", "["),
+			NextMonth: b("next month", "]"),
+			PrevYear:  b("prev year", "pgdown"),
+			NextYear:  b("next year", "pgup"),
+			Jump:      b("type a date", "/"),
+			Pick:      b("pick", "enter"),
+			Cancel:    b("close", "esc"),
+		},
+		Recurrence: RecurrenceKeys{
+			NextRow:   b("next row", "tab"),
+			PrevRow:   b("prev row", "shift+tab"),
+			Left:      b("change", "left", "h"),
+			Right:     b("change", "right", "l"),
+			Up:        b("more", "up", "k"),
+			Down:      b("less", "down", "j"),
+			ToggleDay: b("toggle day", " "),
+			Save:      b("save", "enter"),
+			Cancel:    b("cancel", "esc"),
+		},
+		Delete: DeleteKeys{
+			Occurrence: b("this one", "enter", "o"),
+			Following:  b("this and following", "f"),
+			Series:     b("whole series", "a"),
+			Cancel:     b("cancel", "q", "esc"),
+		},
+		Scope: ScopeKeys{
+			Occurrence: b("only this one", "o"),
+			Series:     b("whole series", "a"),
+			Cancel:     b("cancel", "q", "esc"),
+		},
+		Trash: TrashKeys{
+			Up:      b("up", "up", "k"),
+			Down:    b("down", "down", "j"),
+			Restore: b("restore", "r", "enter"),
+			Purge:   b("delete for good", "x", "delete"),
+			Empty:   b("empty trash", "X"),
+			Close:   b("close", "t", "q", "esc"),
+		},
+		Logbook: LogbookKeys{
+			Up:    b("scroll up", "up", "k"),
+			Down:  b("scroll down", "down", "j"),
+			Close: b("close", "L", "q", "esc"),
+		},
+		Rollover: RolloverKeys{
+			Yes: b("roll over", "y", "enter"),
+			No:  b("keep", "n", "esc", "q"),
+		},
+		Help: HelpKeys{
+			Close: b("close", "?", "q", "esc"),
+		},
+	}
+}
+
+// ShortHelp and FullHelp make the modes usable with bubbles/help.
+
+func (k GridKeys) ShortHelp() []key.Binding {
+	return []key.Binding{k.Toggle, k.New, k.Edit, k.Move, k.Delete, k.Undo, k.Help, k.Quit}
+}
+
+func (k GridKeys) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Left, k.Right, k.Up, k.Down, k.PrevWeek, k.NextWeek},
+		{k.Toggle, k.New, k.Edit, k.Move, k.Recurrence},
+		{k.Delete, k.Undo, k.Redo, k.Details},
+		{k.Trash, k.Logbook, k.Help, k.Quit},
+	}
+}
+
+func (k FormKeys) ShortHelp() []key.Binding {
+	return []key.Binding{k.NextField, k.Submit, k.Save, k.Cancel}
+}
+
+func (k FormKeys) FullHelp() [][]key.Binding { return [][]key.Binding{k.ShortHelp()} }
+
+func (k DetailsKeys) ShortHelp() []key.Binding { return []key.Binding{k.Edit, k.Close} }
+
+func (k DetailsKeys) FullHelp() [][]key.Binding { return [][]key.Binding{k.ShortHelp()} }
+
+func (k MoveKeys) ShortHelp() []key.Binding {
+	return []key.Binding{k.Someday, k.Today, k.Calendar, k.Cancel}
+}
+
+func (k MoveKeys) FullHelp() [][]key.Binding { return [][]key.Binding{k.ShortHelp()} }
+
+func (k CalendarKeys) ShortHelp() []key.Binding {
+	return []key.Binding{k.PrevMonth, k.NextMonth, k.PrevYear, k.NextYear, k.Jump, k.Pick, k.Cancel}
+}
+
+func (k CalendarKeys) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Left, k.Right, k.Up, k.Down},
+		{k.PrevMonth, k.NextMonth, k.PrevYear, k.NextYear},
+		{k.Jump, k.Pick, k.Cancel},
+	}
+}
+
+func (k RecurrenceKeys) ShortHelp() []key.Binding {
+	return []key.Binding{k.NextRow, k.Left, k.ToggleDay, k.Save, k.Cancel}
+}
+
+func (k RecurrenceKeys) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.NextRow, k.PrevRow, k.Left, k.Right, k.Up, k.Down},
+		{k.ToggleDay, k.Save, k.Cancel},
+	}
+}
+
+func (k DeleteKeys) ShortHelp() []key.Binding {
+	return []key.Binding{k.Occurrence, k.Following, k.Series, k.Cancel}
+}
+
+func (k DeleteKeys) FullHelp() [][]key.Binding { return [][]key.Binding{k.ShortHelp()} }
+
+func (k ScopeKeys) ShortHelp() []key.Binding {
+	return []key.Binding{k.Occurrence, k.Series, k.Cancel}
+}
+
+func (k ScopeKeys) FullHelp() [][]key.Binding { return [][]key.Binding{k.ShortHelp()} }
+
+func (k TrashKeys) ShortHelp() []key.Binding {
+	return []key.Binding{k.Restore, k.Purge, k.Empty, k.Close}
+}
+
+func (k TrashKeys) FullHelp() [][]key.Binding {
+	return [][]key.Binding{{k.Up, k.Down, k.Restore, k.Purge, k.Empty, k.Close}}
+}
+
+func (k LogbookKeys) ShortHelp() []key.Binding { return []key.Binding{k.Up, k.Down, k.Close} }
+
+func (k LogbookKeys) FullHelp() [][]key.Binding { return [][]key.Binding{k.ShortHelp()} }
+
+func (k RolloverKeys) ShortHelp() []key.Binding { return []key.Binding{k.Yes, k.No} }
+
+func (k RolloverKeys) FullHelp() [][]key.Binding { return [][]key.Binding{k.ShortHelp()} }
+
+func (k HelpKeys) ShortHelp() []key.Binding { return []key.Binding{k.Close} }
+
+func (k HelpKeys) FullHelp() [][]key.Binding { return [][]key.Binding{k.ShortHelp()} }
+
+// Remap replaces bindings with the keys from overrides, which maps mode
+// to action to keys, as in the [keys.<mode>] tables of the config file.
+// Key names are the ones Bubble Tea reports ("ctrl+s", "pgup", "left"),
+// plus "space".
+func (k *KeyMap) Remap(overrides map[string]map[string][]string) error {
+	modes := reflect.ValueOf(k).Elem()
+	for mode, actions := range overrides {
+		modeField := fieldBySnakeName(modes, mode)
+		if !modeField.IsValid() {
+			return fmt.Errorf("keys: unknown mode %q, expected one of %s", mode, strings.Join(snakeNames(modes.Type()), ", "))
+		}
+		for action, keys := range actions {
+			binding := fieldBySnakeName(modeField, action)
+			if !binding.IsValid() {
+				return fmt.Errorf("keys.%s: unknown action %q, expected one of %s", mode, action, strings.Join(snakeNames(modeField.Type()), ", "))
+			}
+			if len(keys) == 0 {
+				return fmt.Errorf("keys.%s.%s: needs at least one key", mode, action)
+			}
+
+			keys = append([]string(nil), keys...)
+			for i, name := range keys {
+				if name == "space" {
+					keys[i] = " "
+				}
+			}
+			b := binding.Addr().Interface().(*key.Binding)
+			b.SetKeys(keys...)
+			b.SetHelp(keyLabel(keys), b.Help().Desc)
+		}
+	}
+	return nil
+}
+
+// keyLabel is how keys show up in the help, e.g. "←/h".
+func keyLabel(keys []string) string {
+	names := map[string]string{
+		" ":         "space",
+		"left":      "←",
+		"right":     "→",
+		"up":        "↑",
+		"down":      "↓",
+		"backspace": "bksp",
+		"delete":    "del",
+	}
+	var labels []string
+	for _, k := range keys {
+		if name, ok := names[k]; ok {
+			k = name
+		}
+		labels = append(labels, k)
+	}
+	return strings.Join(labels, "/")
+}
+
+func fieldBySnakeName(v reflect.Value, name string) reflect.Value {
+	for i := 0; i < v.NumField(); i++ {
+		if snakeCase(v.Type().Field(i).Name) == name {
+			return v.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+func snakeNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		names = append(names, snakeCase(t.Field(i).Name))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// snakeCase turns a Go field name like NextMonth into next_month.
+func snakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// newHelp styles bubbles/help with the current theme.
+func newHelp() help.Model {
+	h := help.New()
+	keyStyle := lipgloss.NewStyle().Foreground(textColor).Bold(true)
+	descStyle := lipgloss.NewStyle().Foreground(mutedColor)
+	sepStyle := lipgloss.NewStyle().Foreground(dimColor)
+	h.Styles.ShortKey, h.Styles.FullKey = keyStyle, keyStyle
+	h.Styles.ShortDesc, h.Styles.FullDesc = descStyle, descStyle
+	h.Styles.ShortSeparator, h.Styles.FullSeparator, h.Styles.Ellipsis = sepStyle, sepStyle, sepStyle
+	return h
+}
+
+// choiceKey renders the key of b the way dialogs highlight their choices.
+func choiceKey(b key.Binding) string {
+	return choiceStyle.Render(b.Help().Key)
+}
+
+// activeKeys are the bindings of whatever currently has the keyboard.
+func (m Model) activeKeys() help.KeyMap {
+	switch {
+	case m.showRolloverDialog:
+		return m.keys.Rollover
+	case m.showHelp:
+		return m.keys.Help
+	case m.showTrash:
+		return m.keys.Trash
+	case m.showLogbook:
+		return m.keys.Logbook
+	case m.showNewTask, m.showEditTask:
+		return m.keys.Form
+	case m.showConfirmDeleteDialog:
+		return m.keys.Delete
+	case m.showTaskDetails:
+		return m.keys.Details
+	case m.showMoveDialog:
+		return m.keys.Move
+	case m.showMoveDialogWithCalender:
+		return m.keys.Calendar
+	case m.showScopeDialog:
+		return m.keys.Scope
+	case m.showRecurrenceRuleDialog:
+		return m.keys.Recurrence
+	}
+	return m.keys.Grid
+}
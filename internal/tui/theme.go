@@ -0,0 +1,161 @@
+package tui
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/charmbracelet/lipgloss"
+)
+
+//go:embed themes/*.toml
+var bundledThemes embed.FS
+
+// Theme is a named palette. Colors are hex ("#818cf8") or ANSI numbers ("240").
+type Theme struct {
+	Name string `toml:"name"`
+
+	Primary             string `toml:"primary"`
+	PrimaryForeground   string `toml:"primary_foreground"`
+	Secondary           string `toml:"secondary"`
+	SecondaryForeground string `toml:"secondary_foreground"`
+	Accent              string `toml:"accent"`
+	Destructive         string `toml:"destructive"`
+	Today               string `toml:"today"`
+
+	CardBackground string `toml:"card_background"`
+	CardForeground string `toml:"card_foreground"`
+
+	Text              string `toml:"text"`
+	Muted             string `toml:"muted"`
+	Dim               string `toml:"dim"`
+	Border            string `toml:"border"`
+	Surface           string `toml:"surface"`
+	SurfaceForeground string `toml:"surface_foreground"`
+
+	// Plain marks selections with reverse video and borders instead of
+	// colors, for NO_COLOR terminals.
+	Plain bool `toml:"-"`
+}
+
+// Names of the themes that pick one of the bundled ones automatically.
+const (
+	ThemeAuto = "auto"
+	ThemeDark = "dark"
+)
+
+// BundledThemes lists the themes shipped with weektcli.
+func BundledThemes() []string {
+	entries, _ := fs.ReadDir(bundledThemes, "themes")
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".toml"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadTheme finds the theme called name: <name>.toml in dir first, then the
+// bundled themes. "auto" picks dark or light from the terminal background.
+// Colors a theme file leaves out come from the dark theme. With NO_COLOR
+// set the result is marked Plain.
+func LoadTheme(name, dir string) (Theme, error) {
+	if name == "" || name == ThemeAuto {
+		name = ThemeDark
+		if !lipgloss.HasDarkBackground() {
+			name = "light"
+		}
+	}
+
+	t, err := decodeTheme(ThemeDark, bundledThemes, "themes/"+ThemeDark+".toml")
+	if err != nil {
+		return Theme{}, err
+	}
+
+	path := filepath.Join(dir, name+".toml")
+	if _, statErr := os.Stat(path); dir != "" && statErr == nil {
+		t, err = decodeThemeOver(t, name, os.DirFS(dir), name+".toml")
+	} else if _, bundledErr := fs.Stat(bundledThemes, "themes/"+name+".toml"); bundledErr == nil {
+		t, err = decodeThemeOver(t, name, bundledThemes, "themes/"+name+".toml")
+	} else {
+		err = fmt.Errorf("unknown theme %q, expected one of %s or a file %s", name, strings.Join(append([]string{ThemeAuto}, BundledThemes()...), ", "), path)
+	}
+	if err != nil {
+		return Theme{}, err
+	}
+
+	t.Plain = os.Getenv("NO_COLOR") != ""
+	return t, nil
+}
+
+func decodeTheme(name string, fsys fs.FS, file string) (Theme, error) {
+	return decodeThemeOver(Theme{}, name, fsys, file)
+}
+
+var colorPattern = regexp.MustCompile(`^(#[0-9a-fA-F]{3}|#[0-9a-fA-F]{6}|[0-9]{1,3})$`)
+
+// decodeThemeOver reads a theme file on top of base and checks its colors.
+func decodeThemeOver(base Theme, name string, fsys fs.FS, file string) (Theme, error) {
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return Theme{}, err
+	}
+	t := base
+	meta, err := toml.Decode(string(data), &t)
+	if err != nil {
+		return Theme{}, fmt.Errorf("theme %s: %w", name, err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return Theme{}, fmt.Errorf("theme %s: unknown color %q", name, undecoded[0].String())
+	}
+	if t.Name == "" || t.Name == base.Name {
+		t.Name = name
+	}
+
+	for key, c := range t.colors() {
+		if !colorPattern.MatchString(c) {
+			return Theme{}, fmt.Errorf("theme %s: %s: %q is not a color, use \"#rrggbb\" or an ANSI number", name, key, c)
+		}
+	}
+	return t, nil
+}
+
+func (t Theme) colors() map[string]string {
+	return map[string]string{
+		"primary":              t.Primary,
+		"primary_foreground":   t.PrimaryForeground,
+		"secondary":            t.Secondary,
+		"secondary_foreground": t.SecondaryForeground,
+		"accent":               t.Accent,
+		"destructive":          t.Destructive,
+		"today":                t.Today,
+		"card_background":      t.CardBackground,
+		"card_foreground":      t.CardForeground,
+		"text":                 t.Text,
+		"muted":                t.Muted,
+		"dim":                  t.Dim,
+		"border":               t.Border,
+		"surface":              t.Surface,
+		"surface_foreground":   t.SurfaceForeground,
+	}
+}
+
+// mustBundledTheme loads a theme shipped with weektcli, which can't fail.
+func mustBundledTheme(name string) Theme {
+	t, err := decodeTheme(name, bundledThemes, "themes/"+name+".toml")
+	if err != nil {
+		panic(errors.Join(errors.New("bundled theme is broken"), err))
+	}
+	return t
+}
+
+func init() {
+	ApplyTheme(mustBundledTheme(ThemeDark))
+}
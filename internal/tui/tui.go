@@ -1,13 +1,17 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 	"unicode/utf8"
 	"weektcli/env"
 	"weektcli/internal/todo"
 
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -16,161 +20,216 @@ import (
 	"github.com/mattn/go-runewidth"
 )
 
+// The palette and the styles built from it. ApplyTheme fills them in.
 var (
-
 	// colors
-	PrimaryColor      = lipgloss.Color("#818cf8")
-	PrimaryForeground = lipgloss.Color("#101010")
+	PrimaryColor      lipgloss.Color
+	PrimaryForeground lipgloss.Color
+
+	SecondaryColor      lipgloss.Color
+	SecondaryForeground lipgloss.Color
 
-	SecondaryColor      = lipgloss.Color("#2dd4bf")
-	SecondaryForeground = lipgloss.Color("#000000")
+	AccentColor lipgloss.Color
 
-	AccentColor = lipgloss.Color("#fcd34d")
+	DestructiveColor lipgloss.Color
 
-	DestructiveColor = lipgloss.Color("#f87171")
+	todayDayColor lipgloss.Color
 
-	todayDayColor = lipgloss.Color("#f472b6")
+	CardBackgroundColor lipgloss.Color
+	CardForegroundColor lipgloss.Color
 
-	CardBackgroundColor = lipgloss.Color("#1a212b")
-	CardForegroundColor = lipgloss.Color("#ffffff")
+	textColor    lipgloss.Color
+	mutedColor   lipgloss.Color
+	dimColor     lipgloss.Color
+	borderColor  lipgloss.Color
+	surfaceColor lipgloss.Color
+	surfaceText  lipgloss.Color
 
+	// plain is set for NO_COLOR: selections use reverse video instead
+	plain bool
+
+	// UI Styles
+	columnStyle         lipgloss.Style
+	todayStyle          lipgloss.Style
+	titleStyle          lipgloss.Style
+	headerStyle         lipgloss.Style
+	footerStyle         lipgloss.Style
+	choiceStyle         lipgloss.Style
+	highlightedTask     lipgloss.Style
+	dialogBoxStyle      lipgloss.Style
+	deleteBoxStyle      lipgloss.Style
+	taskDetailsBoxStyle lipgloss.Style
+	detailBoxStyle      lipgloss.Style
+	detailHeaderStyle   lipgloss.Style
+	labelStyle          lipgloss.Style
+	valueStyle          lipgloss.Style
+	notesBoxStyle       lipgloss.Style
+	pickerActiveStyle   lipgloss.Style
+	pickerInactiveStyle lipgloss.Style
+	calendarBoxStyle    lipgloss.Style
+	monthHeaderStyle    lipgloss.Style
+	weekdayStyle        lipgloss.Style
+	selectedDayStyle    lipgloss.Style
+	todayDayStyle       lipgloss.Style
+	normalDayStyle      lipgloss.Style
+	ruleActiveStyle     lipgloss.Style
+	ruleInactiveStyle   lipgloss.Style
+	ruleFocusStyle      lipgloss.Style
+)
+
+const (
 	columnMaxWidth  = 42
 	columnMaxHeight = 19
+)
+
+// ApplyTheme makes t the active theme and rebuilds every style from it.
+func ApplyTheme(t Theme) {
+	PrimaryColor = lipgloss.Color(t.Primary)
+	PrimaryForeground = lipgloss.Color(t.PrimaryForeground)
+	SecondaryColor = lipgloss.Color(t.Secondary)
+	SecondaryForeground = lipgloss.Color(t.SecondaryForeground)
+	AccentColor = lipgloss.Color(t.Accent)
+	DestructiveColor = lipgloss.Color(t.Destructive)
+	todayDayColor = lipgloss.Color(t.Today)
+	CardBackgroundColor = lipgloss.Color(t.CardBackground)
+	CardForegroundColor = lipgloss.Color(t.CardForeground)
+	textColor = lipgloss.Color(t.Text)
+	mutedColor = lipgloss.Color(t.Muted)
+	dimColor = lipgloss.Color(t.Dim)
+	borderColor = lipgloss.Color(t.Border)
+	surfaceColor = lipgloss.Color(t.Surface)
+	surfaceText = lipgloss.Color(t.SurfaceForeground)
+	plain = t.Plain
 
-	// UI Styles
 	columnStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color(PrimaryColor)).
-			Padding(0, 1).
-			Width(columnMaxWidth - 2).
-			MaxWidth(columnMaxWidth).
-			Height(columnMaxHeight - 2).
-			MaxHeight(columnMaxHeight)
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(PrimaryColor).
+		Padding(0, 1).
+		Width(columnMaxWidth - 2).
+		MaxWidth(columnMaxWidth).
+		Height(columnMaxHeight - 2).
+		MaxHeight(columnMaxHeight)
 
 	todayStyle = columnStyle.
-			BorderForeground(todayDayColor)
+		BorderForeground(todayDayColor)
 
-	titleStyle = lipgloss.NewStyle().
-			Background(lipgloss.Color(PrimaryColor)).
-			Foreground(lipgloss.Color(PrimaryForeground)).
-			Padding(0, 1).
-			Bold(true)
+	titleStyle = filled(lipgloss.NewStyle(), PrimaryColor, PrimaryForeground).
+		Padding(0, 1).
+		Bold(true)
 
-	headerStyle = lipgloss.NewStyle().
-			Background(SecondaryColor).
-			Foreground(PrimaryForeground).
-			Padding(0, 1).
-			MarginLeft(1).
-			Bold(true)
+	headerStyle = filled(lipgloss.NewStyle(), SecondaryColor, PrimaryForeground).
+		Padding(0, 1).
+		MarginLeft(1).
+		Bold(true)
 
 	footerStyle = lipgloss.NewStyle().
-			Foreground(AccentColor).
-			Align(lipgloss.Center)
+		Foreground(AccentColor).
+		Align(lipgloss.Center)
 
 	choiceStyle = lipgloss.NewStyle().
-			Foreground(SecondaryColor).
-			Bold(true)
+		Foreground(SecondaryColor).
+		Bold(true)
 
-	highlightedTask = lipgloss.NewStyle().
-			Background(SecondaryColor).
-			Foreground(PrimaryForeground)
+	highlightedTask = filled(lipgloss.NewStyle(), SecondaryColor, PrimaryForeground)
 
 	dialogBoxStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(SecondaryColor).
-			Padding(1, 4).
-			Width(60)
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(SecondaryColor).
+		Padding(1, 4).
+		Width(60)
 
 	deleteBoxStyle = dialogBoxStyle.BorderForeground(DestructiveColor).Align(lipgloss.Center)
 
 	taskDetailsBoxStyle = dialogBoxStyle.Width(80).BorderForeground(SecondaryColor)
 
 	detailBoxStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(SecondaryColor).
-			Padding(1, 2).
-			Width(60)
-
-	detailHeaderStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("#1a1a1a")).
-				Background(SecondaryColor).
-				Bold(true).
-				Padding(0, 1).
-				MarginBottom(1)
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(SecondaryColor).
+		Padding(1, 2).
+		Width(60)
+
+	detailHeaderStyle = filled(lipgloss.NewStyle(), SecondaryColor, SecondaryForeground).
+		Bold(true).
+		Padding(0, 1).
+		MarginBottom(1)
 
 	labelStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#6272a4")).
-			Bold(true).
-			Width(12)
+		Foreground(mutedColor).
+		Bold(true).
+		Width(12)
 
 	valueStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#f8f8f2"))
+		Foreground(textColor)
 
 	notesBoxStyle = lipgloss.NewStyle().
-			Border(lipgloss.NormalBorder(), false, false, false, true). // Left border only
-			BorderForeground(lipgloss.Color("#44475a")).
-			PaddingLeft(2).
-			MarginTop(1)
+		Border(lipgloss.NormalBorder(), false, false, false, true). // Left border only
+		BorderForeground(borderColor).
+		PaddingLeft(2).
+		MarginTop(1)
 
-	pickerActiveStyle = lipgloss.NewStyle().
-				Background(SecondaryColor).
-				Foreground(PrimaryForeground).
-				Bold(true).
-				Padding(0, 1)
+	pickerActiveStyle = filled(lipgloss.NewStyle(), SecondaryColor, PrimaryForeground).
+		Bold(true).
+		Padding(0, 1)
 
 	pickerInactiveStyle = lipgloss.NewStyle().
-				Padding(0, 1).
-				Foreground(lipgloss.Color("#f8f8f2"))
+		Padding(0, 1).
+		Foreground(textColor)
 
 	calendarBoxStyle = lipgloss.NewStyle().
-				Border(lipgloss.RoundedBorder()).
-				BorderForeground(SecondaryColor).
-				Padding(1, 2)
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(SecondaryColor).
+		Padding(1, 2)
 
 	monthHeaderStyle = lipgloss.NewStyle().
-				Bold(true).
-				Foreground(PrimaryColor).
-				MarginBottom(1)
+		Bold(true).
+		Foreground(PrimaryColor).
+		MarginBottom(1)
 
 	weekdayStyle = lipgloss.NewStyle().
-			Foreground(SecondaryColor).
-			Width(4).
-			Align(lipgloss.Center)
+		Foreground(SecondaryColor).
+		Width(4).
+		Align(lipgloss.Center)
 
 	// The "Solid" selection look from Shadcn
-	selectedDayStyle = lipgloss.NewStyle().
-				Background(SecondaryColor).
-				Foreground(SecondaryForeground).
-				Bold(true).
-				Width(4).
-				Align(lipgloss.Center)
+	selectedDayStyle = filled(lipgloss.NewStyle(), SecondaryColor, SecondaryForeground).
+		Bold(true).
+		Width(4).
+		Align(lipgloss.Center)
 
 	todayDayStyle = lipgloss.NewStyle().
-			Foreground(todayDayColor).
-			Bold(true).
-			Width(4).
-			Align(lipgloss.Center)
+		Foreground(todayDayColor).
+		Bold(true).
+		Underline(plain).
+		Width(4).
+		Align(lipgloss.Center)
 
 	normalDayStyle = lipgloss.NewStyle().
-			Foreground(PrimaryColor).
-			Width(4).
-			Align(lipgloss.Center)
+		Foreground(PrimaryColor).
+		Width(4).
+		Align(lipgloss.Center)
 
-	ruleActiveStyle = lipgloss.NewStyle().
-			Background(lipgloss.Color(SecondaryColor)).
-			Foreground(lipgloss.Color(SecondaryForeground)).
-			Padding(0, 1).Bold(true)
+	ruleActiveStyle = filled(lipgloss.NewStyle(), SecondaryColor, SecondaryForeground).
+		Padding(0, 1).Bold(true)
 
 	ruleInactiveStyle = lipgloss.NewStyle().
-				Background(lipgloss.Color("#27272a")).
-				Foreground(lipgloss.Color("#ffffff")).
-				Padding(0, 1)
+		Background(surfaceColor).
+		Foreground(surfaceText).
+		Padding(0, 1)
 
 	ruleFocusStyle = lipgloss.NewStyle().
-			Border(lipgloss.NormalBorder(), false, false, false, true).
-			BorderForeground(lipgloss.Color(SecondaryColor)).
-			PaddingLeft(2)
-)
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		BorderForeground(SecondaryColor).
+		PaddingLeft(2)
+}
+
+// filled colors a highlighted element, or reverses it when colors are off
+// so the highlight stays visible.
+func filled(s lipgloss.Style, bg, fg lipgloss.Color) lipgloss.Style {
+	if plain {
+		return s.Reverse(true)
+	}
+	return s.Background(bg).Foreground(fg)
+}
 
 //---------------------------------------------------------------------------------------------------------------------------------
 
@@ -190,7 +249,10 @@ type Model struct {
 	showTaskDetails bool
 
 	editingTaskID uuid.UUID
-	showEditTask  bool
+	editingDay    time.Time
+	// editingOriginal addresses the occurrence, see todo.Occurrence.Original
+	editingOriginal time.Time
+	showEditTask    bool
 
 	showMoveDialog             bool
 	showMoveDialogWithCalender bool
@@ -198,162 +260,275 @@ type Model struct {
 	pickerMonth                time.Month
 	pickerYear                 int
 
+	// typed date expression the calendar jumps to, see todo.ParseDate
+	jumpInput  textinput.Model
+	pickerJump bool
+	jumpErr    string
+
 	showRecurrenceRuleDialog bool
 	ruleFocus                int
 	ruleWeekdayCursor        int
 	tempRule                 todo.RecurrenceRule
 
+	// edits of a recurring task waiting for "only this one" / "whole series"
+	showScopeDialog bool
+	scopeAction     int
+	pendingTask     string
+	pendingNotes    string
+	pendingDate     time.Time
+
+	showRolloverDialog bool
+
+	keys     KeyMap
+	help     help.Model
+	showHelp bool
+
+	showTrash bool
+	trashIdx  int
+
+	// completed tasks, archived ones included, loaded when the logbook opens
+	showLogbook   bool
+	logbook       []todo.Completion
+	logbookScroll int
+
+	// the list as last saved, so saving only writes what changed instead
+	// of overwriting what other weektcli processes stored
+	base todo.List
+
+	store todo.Store
+
+	// undo and redo stack, shared with the CLI
+	history *todo.History
+
+	// reports changes other processes make to the data file
+	watcher *todo.Watcher
+
+	// one-line notice shown above the footer until the next key press
+	statusMsg string
+
 	terminalW int
 	terminalH int
 
+	// ascii draws the grid with plain ASCII, see RenderWeek
+	ascii bool
+
+	opts Options
+
 	columnMaxWidth  int
 	columnMaxHeight int
 }
 
 //---------------------------------------------------------------------------------------------------------------------------------
 
-func (m Model) getTasksForDay(day int) []todo.Item {
-	var filtered []todo.Item
-
-	// 1. Handle Someday
+func (m Model) getTasksForDay(day int) []todo.Occurrence {
+	// Someday items have no date, wrap them so every column has the same shape
 	if day == 7 {
-		for _, it := range *m.todoList {
-			if it.IsSomeday {
-				filtered = append(filtered, it)
-			}
+		var filtered []todo.Occurrence
+		for _, it := range m.todoList.Someday() {
+			filtered = append(filtered, todo.Occurrence{Item: it})
 		}
 		return filtered
 	}
 
-	// 2. Normalize Target Date to Midnight (Prevents hour/minute math issues)
-	tY, tM, tD := m.weekStart.AddDate(0, 0, day).Date()
-	targetDate := time.Date(tY, tM, tD, 0, 0, 0, 0, time.Local)
-	targetDateStr := targetDate.Format("2006-01-02")
+	return m.todoList.OccurrencesOn(m.weekStart.AddDate(0, 0, day))
+}
 
-	for _, it := range *m.todoList {
-		if it.IsSomeday {
-			continue
+// editingItem returns the list entry the open dialog is working on.
+func (m Model) editingItem() *todo.Item {
+	for i := range *m.todoList {
+		if (*m.todoList)[i].ID == m.editingTaskID {
+			return &(*m.todoList)[i]
 		}
+	}
+	return nil
+}
 
-		// Normalize Item Start Date to Midnight
-		sY, sM, sD := it.Date.Date()
-		startDate := time.Date(sY, sM, sD, 0, 0, 0, 0, time.Local)
+// Changes to a recurring task that can target one occurrence or the series.
+const (
+	scopeEdit = iota
+	scopeMove
+)
 
-		isMatch := false
+// editingRecurringOccurrence reports whether the open dialog works on a
+// dated occurrence of a recurring task.
+func (m Model) editingRecurringOccurrence() bool {
+	it := m.editingItem()
+	return it != nil && it.RecurrenceRule != nil && !m.editingDay.IsZero()
+}
 
-		// --- Case A: One-time Task ---
-		if it.RecurrenceRule == nil {
-			if startDate.Equal(targetDate) {
-				isMatch = true
-			}
-		} else {
-			// --- Case B: Recurring Task ---
+// Rows of the recurrence dialog, in tab order.
+const (
+	ruleRowFreq = iota
+	ruleRowInterval
+	ruleRowMode
+	ruleRowNth
+	ruleRowWeekdays
+	ruleRowEnds
+	ruleRowEndValue
+)
 
-			// Never show before the start date
-			if targetDate.Before(startDate) {
-				continue
-			}
+// How a recurring task stops repeating.
+const (
+	ruleEndNever = iota
+	ruleEndUntil
+	ruleEndCount
+)
 
-			rule := it.RecurrenceRule
-			interval := int(rule.Interval)
-			if interval <= 0 {
-				interval = 1
-			}
+// ruleRows lists the dialog rows that apply to the rule being edited.
+func (m Model) ruleRows() []int {
+	if m.tempRule.Freq == todo.None {
+		return []int{ruleRowFreq}
+	}
+
+	rows := []int{ruleRowFreq, ruleRowInterval, ruleRowMode}
+	if m.pickingNth() {
+		rows = append(rows, ruleRowNth)
+	}
+	if m.pickingWeekdays() {
+		rows = append(rows, ruleRowWeekdays)
+	}
+	rows = append(rows, ruleRowEnds)
+	if m.ruleEnd() != ruleEndNever {
+		rows = append(rows, ruleRowEndValue)
+	}
+	return rows
+}
 
-			// Use integer division on days for DST safety
-			daysDiff := int(targetDate.Sub(startDate).Hours() / 24)
+// nthPositions are the choices of the "On" row for monthly and yearly
+// rules, 0 keeps the fixed day of month.
+var nthPositions = []int8{0, 1, 2, 3, 4, -1}
 
-			switch rule.Freq {
-			case todo.Daily:
-				if daysDiff%interval == 0 {
-					isMatch = true
-				}
+// pickingWeekdays reports whether the rule being edited needs weekdays.
+func (m Model) pickingWeekdays() bool {
+	if m.tempRule.AfterCompletion {
+		return false
+	}
+	return m.tempRule.Freq == todo.Weekly || (m.tempRule.Nth != 0 && m.pickingNth())
+}
 
-			case todo.Weekly:
-				weekdayMatch := false
+// pickingNth reports whether the "On" row of monthly and yearly rules applies.
+func (m Model) pickingNth() bool {
+	return !m.tempRule.AfterCompletion && (m.tempRule.Freq == todo.Monthly || m.tempRule.Freq == todo.Yearly)
+}
 
-				// If no weekdays were selected (null/empty),
-				// default to the weekday of the task's original start date.
-				if len(rule.Weekdays) == 0 {
-					if targetDate.Weekday() == startDate.Weekday() {
-						weekdayMatch = true
-					}
-				} else {
-					// Normal matching logic
-					for _, wd := range rule.Weekdays {
-						if wd == targetDate.Weekday() {
-							weekdayMatch = true
-							break
-						}
-					}
-				}
+// stepNth moves the "On" row of monthly and yearly rules left or right.
+func (m *Model) stepNth(step int) {
+	current := 0
+	for i, n := range nthPositions {
+		if n == m.tempRule.Nth {
+			current = i
+			break
+		}
+	}
+	next := current + step
+	if next < 0 || next >= len(nthPositions) {
+		return
+	}
+	m.tempRule.Nth = nthPositions[next]
 
-				if weekdayMatch {
-					// Calculate weeks diff safely
-					weeksDiff := daysDiff / 7
-					if weeksDiff%interval == 0 {
-						isMatch = true
-					}
-				}
-			case todo.Monthly:
-				// 1. Calculate how many months have passed since the start date
-				startYear, startMonth, startDay := startDate.Date()
-				targetYear, targetMonth, targetDay := targetDate.Date()
-
-				// Formula for total months difference: (YearDiff * 12) + MonthDiff
-				monthsDiff := (targetYear-startYear)*12 + int(targetMonth-startMonth)
-
-				// 2. Check if the month hits our interval (e.g., every 1 month, every 3 months)
-				if monthsDiff >= 0 && monthsDiff%interval == 0 {
-
-					// 3. Check if today's day (e.g., the 26th) matches the target day
-					// If MonthDay is 0 (default), use the day from the task's original start date
-					matchDay := int(rule.MonthDay)
-					if matchDay <= 0 {
-						matchDay = startDay
-					}
+	// Seed the weekday from the task so "2nd ..." has something to pick
+	if m.tempRule.Nth != 0 && len(m.tempRule.Weekdays) == 0 {
+		if it := m.editingItem(); it != nil {
+			m.tempRule.Weekdays = []time.Weekday{it.Date.Weekday()}
+		}
+	}
+}
 
-					// Handle the match
-					if targetDay == matchDay {
-						isMatch = true
-					} else {
-						// OPTIONAL: "End of month" safety.
-						// If the task is set for the 31st, but this month only has 30 days,
-						// show it on the last day of the month.
-						lastDayOfMonth := time.Date(targetYear, targetMonth+1, 0, 0, 0, 0, 0, time.Local).Day()
-						if matchDay > lastDayOfMonth && targetDay == lastDayOfMonth {
-							isMatch = true
-						}
-					}
-				}
-			}
+func (m Model) ruleEnd() int {
+	switch {
+	case m.tempRule.Until != nil:
+		return ruleEndUntil
+	case m.tempRule.Count > 0:
+		return ruleEndCount
+	}
+	return ruleEndNever
+}
 
-			// Handle Completion for Recurring Tasks
-			if isMatch {
-				it.Done = false // The master 'Done' is ignored for recurring instances
-				for _, doneDate := range rule.DoneList {
-					if doneDate == targetDateStr {
-						it.Done = true
-						break
-					}
-				}
-			}
+// setRuleEnd switches the end condition, seeding a sensible default value.
+func (m *Model) setRuleEnd(end int) {
+	if end < ruleEndNever || end > ruleEndCount || end == m.ruleEnd() {
+		return
+	}
+
+	m.tempRule.Until = nil
+	m.tempRule.Count = 0
+
+	switch end {
+	case ruleEndUntil:
+		start := time.Now()
+		if it := m.editingItem(); it != nil && it.Date.After(start) {
+			start = it.Date
 		}
+		y, mo, d := start.AddDate(0, 1, 0).Date()
+		until := time.Date(y, mo, d, 0, 0, 0, 0, time.Local)
+		m.tempRule.Until = &until
+	case ruleEndCount:
+		m.tempRule.Count = 10
+	}
+}
 
-		if isMatch {
-			filtered = append(filtered, it)
+// stepRuleEndValue moves the until date by days, or the count by one per step.
+func (m *Model) stepRuleEndValue(days int) {
+	switch m.ruleEnd() {
+	case ruleEndUntil:
+		until := m.tempRule.Until.AddDate(0, 0, days)
+		if it := m.editingItem(); it != nil && until.Before(it.Date) {
+			return
+		}
+		m.tempRule.Until = &until
+	case ruleEndCount:
+		step := 1
+		if days < 0 {
+			step = -1
+		}
+		if int(m.tempRule.Count)+step >= 1 {
+			m.tempRule.Count = uint16(int(m.tempRule.Count) + step)
 		}
 	}
-	return filtered
 }
 
 //---------------------------------------------------------------------------------------------------------------------------------
 
-func InitialModel(l *todo.List) Model {
+// Options are the user settings the TUI honours, see internal/config.
+type Options struct {
+	WeekStart   time.Weekday
+	DefaultView string // "today", "week" or "someday"
+
+	ColumnWidth  int
+	ColumnHeight int
+
+	DayFormat  string // day column headers
+	DateFormat string // full dates in the details view
+
+	Rollover todo.RolloverMode
+
+	Keys KeyMap
+}
+
+// DefaultOptions is the look and behaviour without a config file.
+func DefaultOptions() Options {
+	return Options{
+		WeekStart:    time.Monday,
+		DefaultView:  "today",
+		ColumnWidth:  columnMaxWidth,
+		ColumnHeight: columnMaxHeight,
+		DayFormat:    "Monday, Jan 02",
+		DateFormat:   "Monday, Jan 02, 2006",
+		Rollover:     todo.RolloverAuto,
+		Keys:         DefaultKeyMap(),
+	}
+}
+
+func InitialModel(l *todo.List, store todo.Store, opts Options) Model {
 	now := time.Now()
-	offset := int(now.Weekday()) - int(time.Monday)
-	if offset < 0 {
-		offset += 7
+	offset := (int(now.Weekday()) - int(opts.WeekStart) + 7) % 7
+
+	cursorDay := offset
+	switch opts.DefaultView {
+	case "week":
+		cursorDay = 0
+	case "someday":
+		cursorDay = 7
 	}
 
 	ti := textinput.New()
@@ -362,16 +537,29 @@ func InitialModel(l *todo.List) Model {
 	ti.CharLimit = 156
 	ti.Width = 30
 
+	ji := textinput.New()
+	ji.Placeholder = "next fri, in 3 days..."
+	ji.CharLimit = 40
+	ji.Width = 22
+
 	ta := textarea.New()
 	ta.Placeholder = "Add some notes here..."
 	ta.SetWidth(30)
 	ta.SetHeight(3)
 
-	return Model{
+	m := Model{
 		todoList:                   l,
+		base:                       l.Snapshot(),
+		store:                      store,
+		history:                    todo.NewHistory(todo.HistoryName(store.Path())),
+		watcher:                    todo.Watch(store.Path()),
 		weekStart:                  now.AddDate(0, 0, -offset),
-		cursorDay:                  offset,
+		cursorDay:                  cursorDay,
+		opts:                       opts,
+		keys:                       opts.Keys,
+		help:                       newHelp(),
 		textInput:                  ti,
+		jumpInput:                  ji,
 		noteInput:                  ta,
 		showNewTask:                false,
 		cursorIdx:                  0,
@@ -382,13 +570,151 @@ func InitialModel(l *todo.List) Model {
 		showMoveDialogWithCalender: false,
 		ruleFocus:                  0,
 		ruleWeekdayCursor:          1,
-		columnMaxWidth:             columnMaxWidth,
-		columnMaxHeight:            columnMaxHeight,
+		columnMaxWidth:             opts.ColumnWidth,
+		columnMaxHeight:            opts.ColumnHeight,
+	}
+
+	// Bring unfinished tasks from past days forward, Tweek style
+	switch opts.Rollover {
+	case todo.RolloverAuto:
+		if moved := l.Rollover(now); len(moved) > 0 {
+			m.statusMsg = fmt.Sprintf("Rolled %d unfinished task(s) over to today", len(moved))
+			m.save()
+		}
+	case todo.RolloverPrompt:
+		m.showRolloverDialog = len(l.Overdue(now)) > 0
+	}
+
+	return m
+}
+
+// save writes the tasks changed since the last save to the store, keeping
+// whatever other processes wrote, and records the change so it can be
+// undone. Failures are reported in the status line instead of being lost
+// silently.
+func (m *Model) save() {
+	if err := todo.SaveChanges(m.store, m.base, *m.todoList); err != nil {
+		m.statusMsg = "Could not save: " + err.Error()
+		return
+	}
+	if err := m.history.Record(m.base, *m.todoList); err != nil {
+		m.statusMsg = "Saved, but could not record the undo history: " + err.Error()
+	}
+	m.base = m.todoList.Snapshot()
+}
+
+// stepHistory runs an undo or redo and saves the result, which is not
+// recorded as a change of its own.
+func (m *Model) stepHistory(step func(*todo.List, func() error) (todo.Command, error), done string) {
+	c, err := step(m.todoList, func() error {
+		return todo.SaveChanges(m.store, m.base, *m.todoList)
+	})
+	switch {
+	case errors.Is(err, todo.ErrNothingToUndo), errors.Is(err, todo.ErrNothingToRedo):
+		m.statusMsg = strings.ToUpper(err.Error()[:1]) + err.Error()[1:]
+		return
+	case err != nil:
+		m.statusMsg = "Could not save: " + err.Error()
+		return
+	}
+	m.base = m.todoList.Snapshot()
+	m.statusMsg = done + ": " + c.String()
+
+	if tasks := m.getTasksForDay(m.cursorDay); m.cursorIdx >= len(tasks) {
+		m.cursorIdx = max(len(tasks)-1, 0)
+	}
+}
+
+// fileChangedMsg is sent when the data file changed on disk.
+type fileChangedMsg struct{}
+
+func waitForFileChange(w *todo.Watcher) tea.Cmd {
+	return func() tea.Msg {
+		<-w.Changes()
+		return fileChangedMsg{}
+	}
+}
+
+// reload merges changes another process saved into the list, keeping the
+// cursor on the same task and warning when the task an open dialog works
+// on was changed or deleted.
+func (m *Model) reload() {
+	disk, err := m.store.Load()
+	if err != nil && !errors.Is(err, todo.ErrRestoredBackup) {
+		if !errors.Is(err, os.ErrNotExist) {
+			m.statusMsg = "Could not reload: " + err.Error()
+		}
+		return
+	}
+
+	changed, removed := todo.Diff(m.base, disk)
+	if len(changed) == 0 && len(removed) == 0 {
+		return // our own save
+	}
+
+	// remember the selected task to find it again afterwards
+	var selectedID uuid.UUID
+	var selectedDay time.Time
+	if tasks := m.getTasksForDay(m.cursorDay); m.cursorIdx < len(tasks) {
+		selectedID = tasks[m.cursorIdx].ID
+		selectedDay = tasks[m.cursorIdx].Day
+	}
+
+	*m.todoList = todo.Merge(m.base, *m.todoList, disk)
+	m.base = disk.Snapshot()
+
+	tasks := m.getTasksForDay(m.cursorDay)
+	for i, t := range tasks {
+		if t.ID == selectedID && t.Day.Equal(selectedDay) {
+			m.cursorIdx = i
+			break
+		}
+	}
+	if m.cursorIdx >= len(tasks) {
+		m.cursorIdx = max(len(tasks)-1, 0)
+	}
+
+	// the task an open dialog is working on
+	var openID uuid.UUID
+	switch {
+	case m.showEditTask, m.showMoveDialog, m.showMoveDialogWithCalender, m.showRecurrenceRuleDialog, m.showScopeDialog:
+		openID = m.editingTaskID
+	case m.showTaskDetails && m.selectedTask != nil:
+		openID = m.selectedTask.ID
+	case m.showConfirmDeleteDialog:
+		openID = selectedID
+	default:
+		return
+	}
+
+	for _, id := range removed {
+		if id == openID {
+			m.showEditTask = false
+			m.showMoveDialog = false
+			m.showMoveDialogWithCalender = false
+			m.showRecurrenceRuleDialog = false
+			m.showScopeDialog = false
+			m.showTaskDetails = false
+			m.showConfirmDeleteDialog = false
+			m.statusMsg = "The task you had open was deleted in another window"
+			return
+		}
+	}
+	for _, id := range changed {
+		if id == openID {
+			if m.showTaskDetails {
+				if task, err := m.todoList.GetTaskDetails(id.String()); err == nil {
+					m.selectedTask = &task
+				}
+			}
+			m.statusMsg = "The task you had open was changed in another window"
+			return
+		}
 	}
 }
 
 func (m Model) Init() tea.Cmd {
-	return nil
+	return waitForFileChange(m.watcher)
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -400,16 +726,87 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.terminalW = msg.Width
 		m.terminalH = msg.Height
+		m.help.Width = msg.Width
 		return m, nil
 
+	case fileChangedMsg:
+		m.reload()
+		return m, waitForFileChange(m.watcher)
+
 	case tea.KeyMsg:
 		tasks := m.getTasksForDay(m.cursorDay)
-
-		if m.showNewTask {
+		m.statusMsg = ""
+
+		if m.showRolloverDialog {
+			// move unfinished tasks from past days to today
+			switch {
+			case key.Matches(msg, m.keys.Rollover.Yes):
+				moved := m.todoList.Rollover(time.Now())
+				m.statusMsg = fmt.Sprintf("Rolled %d unfinished task(s) over to today", len(moved))
+				m.save()
+				m.showRolloverDialog = false
+				return m, nil
+			case key.Matches(msg, m.keys.Rollover.No):
+				m.showRolloverDialog = false
+				return m, nil
+			}
+			return m, nil
+		} else if m.showHelp {
+			if key.Matches(msg, m.keys.Help.Close) {
+				m.showHelp = false
+			}
+			return m, nil
+		} else if m.showLogbook {
+			switch {
+			case key.Matches(msg, m.keys.Logbook.Close):
+				m.showLogbook = false
+			case key.Matches(msg, m.keys.Logbook.Up):
+				m.logbookScroll = max(m.logbookScroll-1, 0)
+			case key.Matches(msg, m.keys.Logbook.Down):
+				m.logbookScroll = min(m.logbookScroll+1, max(len(m.logbookLines())-m.logbookHeight(), 0))
+			}
+			return m, nil
+		} else if m.showTrash {
+			// deleted tasks, most recent first
+			trash := m.todoList.Trash()
+			k := m.keys.Trash
+			switch {
+			case key.Matches(msg, k.Close):
+				m.showTrash = false
+			case key.Matches(msg, k.Up):
+				if m.trashIdx > 0 {
+					m.trashIdx--
+				}
+			case key.Matches(msg, k.Down):
+				if m.trashIdx < len(trash)-1 {
+					m.trashIdx++
+				}
+			case key.Matches(msg, k.Restore):
+				if m.trashIdx < len(trash) {
+					m.todoList.Restore(trash[m.trashIdx].ID)
+					m.statusMsg = fmt.Sprintf("Restored %q", trash[m.trashIdx].Task)
+					m.save()
+				}
+			case key.Matches(msg, k.Purge):
+				if m.trashIdx < len(trash) {
+					m.todoList.Purge(trash[m.trashIdx].ID)
+					m.save()
+				}
+			case key.Matches(msg, k.Empty):
+				if purged := m.todoList.PurgeTrash(time.Time{}); len(purged) > 0 {
+					m.statusMsg = fmt.Sprintf("Deleted %d task(s) for good", len(purged))
+					m.save()
+				}
+			}
+			if n := len(m.todoList.Trash()); m.trashIdx >= n {
+				m.trashIdx = max(n-1, 0)
+			}
+			return m, nil
+		} else if m.showNewTask {
 			// add new task
-			switch msg.String() {
+			switch {
 
-			case "tab":
+			case key.Matches(msg, m.keys.Form.NextField):
 				if m.textInput.Focused() {
 					m.textInput.Blur()
 					m.noteInput.Focus()
@@ -419,33 +816,23 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				return m, nil
 
-			case "enter":
-				if m.textInput.Focused() {
-					taskName := m.textInput.Value()
-					noteText := m.noteInput.Value()
-					if taskName != "" {
-						isSomeday := m.cursorDay == 7
-						taskDate := m.weekStart.AddDate(0, 0, m.cursorDay)
-						m.todoList.Add(taskName, noteText, taskDate, isSomeday)
-						m.todoList.Save(env.TodoFileName)
+			case key.Matches(msg, m.keys.Form.Save), key.Matches(msg, m.keys.Form.Submit) && m.textInput.Focused():
+				if strings.TrimSpace(m.textInput.Value()) != "" {
+					item, err := m.newTask()
+					if err != nil {
+						// the preview under the title shows what is wrong
+						return m, nil
 					}
-					m.showNewTask = false
-					m.textInput.Reset()
-					m.noteInput.Reset()
-					return m, nil
-				}
-			case "ctrl+s":
-				taskName := m.textInput.Value()
-				if taskName != "" {
-					m.todoList.Add(taskName, m.noteInput.Value(), m.weekStart.AddDate(0, 0, m.cursorDay), m.cursorDay == 7)
-					m.todoList.Save(env.TodoFileName)
+					item.Notes = m.noteInput.Value()
+					m.todoList.AddItem(item)
+					m.save()
 				}
 				m.showNewTask = false
 				m.textInput.Reset()
 				m.noteInput.Reset()
 				return m, nil
 
-			case "esc":
+			case key.Matches(msg, m.keys.Form.Cancel):
 				m.showNewTask = false
 				m.textInput.Reset()
 				m.noteInput.Reset()
@@ -454,14 +841,32 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else if m.showConfirmDeleteDialog {
 
 			// delete task
-			switch msg.String() {
-			case "enter":
+			k := m.keys.Delete
+			switch {
+			case key.Matches(msg, k.Occurrence, k.Following, k.Series):
 				tasks := m.getTasksForDay(m.cursorDay)
 
 				if len(tasks) > 0 && m.cursorIdx < len(tasks) {
-					idToDelete := tasks[m.cursorIdx].ID.String()
+					selected := tasks[m.cursorIdx]
 
-					m.todoList.DeleteTask(env.TodoFileName, idToDelete)
+					switch {
+					case selected.RecurrenceRule == nil || selected.Day.IsZero():
+						// One-time tasks can only be deleted as a whole
+						if !key.Matches(msg, k.Occurrence) {
+							return m, nil
+						}
+						m.todoList.DeleteTask(selected.ID.String())
+						m.save()
+					case key.Matches(msg, k.Series): // whole series
+						m.todoList.DeleteTask(selected.ID.String())
+						m.save()
+					case key.Matches(msg, k.Following): // this and following
+						m.todoList.EndSeriesBefore(selected.ID, selected.Original)
+						m.save()
+					default: // this occurrence
+						m.todoList.SkipOccurrence(selected.ID, selected.Original)
+						m.save()
+					}
 
 					if m.cursorIdx > 0 && m.cursorIdx >= len(tasks)-1 {
 						m.cursorIdx--
@@ -469,23 +874,24 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				m.showConfirmDeleteDialog = false
 				return m, cmd
-			case "q", "esc":
+			case key.Matches(msg, k.Cancel):
 				m.showConfirmDeleteDialog = false
 				return m, cmd
 			}
 		} else if m.showTaskDetails {
 
 			//task details
-			switch msg.String() {
-			case "q", "esc":
+			switch {
+			case key.Matches(msg, m.keys.Details.Close):
 				m.showTaskDetails = false
 				return m, cmd
-			case "e": // go to edit
+			case key.Matches(msg, m.keys.Details.Edit): // go to edit
 				tasks := m.getTasksForDay(m.cursorDay)
 				if len(tasks) > 0 && m.cursorIdx < len(tasks) {
 					selected := tasks[m.cursorIdx]
 
 					m.editingTaskID = selected.ID
+					m.editingDay, m.editingOriginal = selected.Day, selected.Original
 
 					m.textInput.SetValue(selected.Task)
 					m.noteInput.SetValue(selected.Notes)
@@ -502,11 +908,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else if m.showEditTask {
 
 			// edit task title and note
-			switch msg.String() {
-			case "esc":
+			switch {
+			case key.Matches(msg, m.keys.Form.Cancel):
 				m.showEditTask = false
 				return m, cmd
-			case "tab":
+			case key.Matches(msg, m.keys.Form.NextField):
 				if m.textInput.Focused() {
 					m.textInput.Blur()
 					m.noteInput.Focus()
@@ -515,44 +921,54 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.textInput.Focus()
 				}
 				return m, nil
-			case "enter", "ctrl+s":
-				if msg.String() == "ctrl+s" || (msg.String() == "enter" && m.textInput.Focused()) {
-					taskName := m.textInput.Value()
-					noteText := m.noteInput.Value()
+			case key.Matches(msg, m.keys.Form.Save), key.Matches(msg, m.keys.Form.Submit) && m.textInput.Focused():
+				taskName := m.textInput.Value()
+				noteText := m.noteInput.Value()
 
-					if taskName != "" {
+				if taskName != "" {
+					if m.editingRecurringOccurrence() {
+						// Ask before touching the whole series
+						m.pendingTask = taskName
+						m.pendingNotes = noteText
+						m.scopeAction = scopeEdit
+						m.showScopeDialog = true
+					} else {
 						m.todoList.UpdateTask(m.editingTaskID, taskName, noteText)
 
-						m.todoList.Save(env.TodoFileName)
+						m.save()
 					}
-
-					m.showEditTask = false
-					m.textInput.Reset()
-					m.noteInput.Reset()
-					return m, nil
 				}
+
+				m.showEditTask = false
+				m.textInput.Reset()
+				m.noteInput.Reset()
+				return m, nil
 			}
 
 		} else if m.showMoveDialog {
 
 			// move task dialog
-			switch msg.String() {
-			case "esc":
+			switch {
+			case key.Matches(msg, m.keys.Move.Cancel):
 				m.showMoveDialog = false
 				return m, cmd
-			case "s":
-				for i := range *m.todoList {
-					if (*m.todoList)[i].ID == m.editingTaskID {
-						(*m.todoList)[i].IsSomeday = true
-						(*m.todoList)[i].Date = time.Time{}
-						break
-					}
-				}
-				m.todoList.Save(env.TodoFileName)
+			case key.Matches(msg, m.keys.Move.Someday):
 				m.showMoveDialog = false
+				if err := m.todoList.MoveToSomeday(m.editingTaskID); err != nil {
+					m.statusMsg = err.Error()
+					return m, nil
+				}
+				m.save()
 				return m, nil
-			case "t":
+			case key.Matches(msg, m.keys.Move.Today):
 				now := time.Now()
+				if m.editingRecurringOccurrence() {
+					m.pendingDate = now
+					m.scopeAction = scopeMove
+					m.showScopeDialog = true
+					m.showMoveDialog = false
+					return m, nil
+				}
 				for i := range *m.todoList {
 					if (*m.todoList)[i].ID == m.editingTaskID {
 						(*m.todoList)[i].IsSomeday = false
@@ -560,10 +976,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						break
 					}
 				}
-				m.todoList.Save(env.TodoFileName)
+				m.save()
 				m.showMoveDialog = false
 				return m, nil
-			case "c":
+			case key.Matches(msg, m.keys.Move.Calendar):
 				now := time.Now()
 				m.pickerDay = now.Day()
 				m.pickerMonth = now.Month()
@@ -574,22 +990,60 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 			}
 
+		} else if m.showMoveDialogWithCalender && m.pickerJump {
+
+			// typing a date to jump to
+			switch {
+			case key.Matches(msg, m.keys.Form.Cancel):
+				m.pickerJump = false
+				m.jumpInput.Blur()
+				return m, nil
+			case key.Matches(msg, m.keys.Form.Submit):
+				day, someday, err := todo.ParseDate(m.jumpInput.Value(), time.Now(), m.opts.WeekStart)
+				if err != nil {
+					m.jumpErr = err.Error()
+					return m, nil
+				}
+				m.pickerJump = false
+				m.jumpInput.Blur()
+				if someday {
+					m.showMoveDialogWithCalender = false
+					if err := m.todoList.MoveToSomeday(m.editingTaskID); err != nil {
+						m.statusMsg = err.Error()
+						return m, nil
+					}
+					m.save()
+					return m, nil
+				}
+				m.pickerYear, m.pickerMonth, m.pickerDay = day.Date()
+				return m, nil
+			}
+			m.jumpErr = ""
+			m.jumpInput, cmd = m.jumpInput.Update(msg)
+			return m, cmd
+
 		} else if m.showMoveDialogWithCalender {
 			daysInMonth := time.Date(m.pickerYear, m.pickerMonth+1, 0, 0, 0, 0, 0, time.Local).Day()
 
-			switch msg.String() {
-			case "esc":
+			k := m.keys.Calendar
+			switch {
+			case key.Matches(msg, k.Cancel):
 				m.showMoveDialogWithCalender = false
 				return m, nil
+			case key.Matches(msg, k.Jump):
+				m.pickerJump = true
+				m.jumpErr = ""
+				m.jumpInput.Reset()
+				return m, m.jumpInput.Focus()
 
 			// --- MONTH NAVIGATION ---
-			case "]": // Next Month
+			case key.Matches(msg, k.NextMonth):
 				m.pickerMonth++
 				if m.pickerMonth > 12 {
 					m.pickerMonth = 1
 					m.pickerYear++
 				}
-			case "[": // Previous Month
+			case key.Matches(msg, k.PrevMonth):
 				m.pickerMonth--
 				if m.pickerMonth < 1 {
 					m.pickerMonth = 12
@@ -597,55 +1051,42 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 
 			// --- YEAR NAVIGATION ---
-			case "pgup": // Next Year
+			case key.Matches(msg, k.NextYear):
 				m.pickerYear++
-			case "pgdown": // Previous Year
+			case key.Matches(msg, k.PrevYear):
 				m.pickerYear--
 
 			// --- DAY NAVIGATION ---
-			case "left", "h":
+			case key.Matches(msg, k.Left):
 				if m.pickerDay > 1 {
 					m.pickerDay--
 				}
-			case "right", "l":
+			case key.Matches(msg, k.Right):
 				if m.pickerDay < daysInMonth {
 					m.pickerDay++
 				}
-			case "up", "k":
+			case key.Matches(msg, k.Up):
 				if m.pickerDay > 7 {
 					m.pickerDay -= 7
 				}
-			case "down", "j":
+			case key.Matches(msg, k.Down):
 				if m.pickerDay <= daysInMonth-7 {
 					m.pickerDay += 7
 				}
 
-			case "enter":
+			case key.Matches(msg, k.Pick):
 				targetDate := time.Date(m.pickerYear, m.pickerMonth, m.pickerDay, 0, 0, 0, 0, time.Local)
+				m.showMoveDialogWithCalender = false
 
-				for i := range *m.todoList {
-					if (*m.todoList)[i].ID == m.editingTaskID {
-						item := &(*m.todoList)[i]
-
-						item.Date = targetDate
-						item.IsSomeday = false
-
-						if item.RecurrenceRule != nil {
-							switch item.RecurrenceRule.Freq {
-
-							case todo.Weekly:
-								item.RecurrenceRule.Weekdays = []time.Weekday{targetDate.Weekday()}
-
-							case todo.Monthly:
-								item.RecurrenceRule.MonthDay = uint8(targetDate.Day())
-							}
-						}
-						break
-					}
+				if m.editingRecurringOccurrence() {
+					m.pendingDate = targetDate
+					m.scopeAction = scopeMove
+					m.showScopeDialog = true
+					return m, nil
 				}
 
-				m.todoList.Save(env.TodoFileName)
-				m.showMoveDialogWithCalender = false
+				m.todoList.MoveTask(m.editingTaskID, targetDate)
+				m.save()
 				return m, nil
 			}
 
@@ -653,54 +1094,119 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.pickerDay > newDaysInMonth {
 				m.pickerDay = newDaysInMonth
 			}
+		} else if m.showScopeDialog {
+
+			// apply a pending edit/move to one occurrence or the whole series
+			switch {
+			case key.Matches(msg, m.keys.Scope.Cancel):
+				m.showScopeDialog = false
+				return m, nil
+			case key.Matches(msg, m.keys.Scope.Occurrence):
+				switch m.scopeAction {
+				case scopeEdit:
+					m.todoList.UpdateOccurrence(m.editingTaskID, m.editingOriginal, m.pendingTask, m.pendingNotes)
+				case scopeMove:
+					m.todoList.MoveOccurrence(m.editingTaskID, m.editingOriginal, m.pendingDate)
+				}
+				m.save()
+				m.showScopeDialog = false
+				return m, nil
+			case key.Matches(msg, m.keys.Scope.Series):
+				switch m.scopeAction {
+				case scopeEdit:
+					m.todoList.UpdateTask(m.editingTaskID, m.pendingTask, m.pendingNotes)
+				case scopeMove:
+					m.todoList.MoveTask(m.editingTaskID, m.pendingDate)
+				}
+				m.save()
+				m.showScopeDialog = false
+				return m, nil
+			}
+
 		} else if m.showRecurrenceRuleDialog {
-			switch msg.String() {
-			case "esc":
+			k := m.keys.Recurrence
+			switch {
+			case key.Matches(msg, k.Cancel):
 				m.showRecurrenceRuleDialog = false
 				return m, nil
 
-			case "tab":
-				m.ruleFocus = (m.ruleFocus + 1) % 2
+			case key.Matches(msg, k.NextRow, k.PrevRow):
+				rows := m.ruleRows()
+				current := 0
+				for i, row := range rows {
+					if row == m.ruleFocus {
+						current = i
+						break
+					}
+				}
+				if key.Matches(msg, k.NextRow) {
+					current = (current + 1) % len(rows)
+				} else {
+					current = (current + len(rows) - 1) % len(rows)
+				}
+				m.ruleFocus = rows[current]
 				return m, nil
 
-			case "left", "h":
+			case key.Matches(msg, k.Left):
 				switch m.ruleFocus {
-				case 0: // Change Frequency
+				case ruleRowFreq: // Change Frequency
 					if m.tempRule.Freq > 0 {
 						m.tempRule.Freq--
 					}
-				case 2: // Move Weekday Cursor
+				case ruleRowMode:
+					m.tempRule.AfterCompletion = false
+				case ruleRowNth:
+					m.stepNth(-1)
+				case ruleRowWeekdays: // Move Weekday Cursor
 					if m.ruleWeekdayCursor > 0 {
 						m.ruleWeekdayCursor--
 					}
+				case ruleRowEnds:
+					m.setRuleEnd(m.ruleEnd() - 1)
+				case ruleRowEndValue:
+					m.stepRuleEndValue(-1)
 				}
 
-			case "right", "l":
+			case key.Matches(msg, k.Right):
 				switch m.ruleFocus {
-				case 0: // Change Frequency
-					if m.tempRule.Freq < 3 {
+				case ruleRowFreq: // Change Frequency
+					if m.tempRule.Freq < todo.Yearly {
 						m.tempRule.Freq++
 					}
-				case 2: // Move Weekday Cursor
+				case ruleRowMode:
+					m.tempRule.AfterCompletion = true
+				case ruleRowNth:
+					m.stepNth(1)
+				case ruleRowWeekdays: // Move Weekday Cursor
 					if m.ruleWeekdayCursor < 6 {
 						m.ruleWeekdayCursor++
 					}
+				case ruleRowEnds:
+					m.setRuleEnd(m.ruleEnd() + 1)
+				case ruleRowEndValue:
+					m.stepRuleEndValue(1)
 				}
 
-			case "up", "k":
-				if m.ruleFocus == 1 { // Increase Interval
+			case key.Matches(msg, k.Up):
+				switch m.ruleFocus {
+				case ruleRowInterval: // Increase Interval
 					m.tempRule.Interval++
+				case ruleRowEndValue:
+					m.stepRuleEndValue(7)
 				}
 
-			case "down", "j":
-				if m.ruleFocus == 1 { // Decrease Interval
+			case key.Matches(msg, k.Down):
+				switch m.ruleFocus {
+				case ruleRowInterval: // Decrease Interval
 					if m.tempRule.Interval > 1 {
 						m.tempRule.Interval--
 					}
+				case ruleRowEndValue:
+					m.stepRuleEndValue(-7)
 				}
 
-			case " ": // Toggle Weekday (Space)
-				if m.ruleFocus == 2 && m.tempRule.Freq == todo.Weekly {
+			case key.Matches(msg, k.ToggleDay):
+				if m.ruleFocus == ruleRowWeekdays && m.pickingWeekdays() {
 					// Map cursor (0-6) to time.Weekday (Mon=1 ... Sun=0)
 					// Go's Sunday is 0, Monday is 1
 					targetWD := time.Weekday((m.ruleWeekdayCursor + 1) % 7)
@@ -723,65 +1229,110 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 				}
 
-			case "enter":
+			case key.Matches(msg, k.Save):
+				// "Nth weekday" only means something for monthly and yearly tasks
+				if !m.pickingNth() {
+					m.tempRule.Nth = 0
+				}
+
 				// SAFETY: If Weekly mode is selected but NO weekdays are picked,
 				// add the weekday of the original task date automatically.
-				if m.tempRule.Freq == todo.Weekly && len(m.tempRule.Weekdays) == 0 {
-					// Find the original task in the list
-					for _, it := range *m.todoList {
-						if it.ID == m.editingTaskID {
-							m.tempRule.Weekdays = []time.Weekday{it.Date.Weekday()}
-							break
-						}
+				if m.pickingWeekdays() && len(m.tempRule.Weekdays) == 0 {
+					if it := m.editingItem(); it != nil {
+						m.tempRule.Weekdays = []time.Weekday{it.Date.Weekday()}
 					}
 				}
 
-				m.todoList.UpdateRecurrenceRule(m.editingTaskID, m.tempRule)
-				m.todoList.Save(env.TodoFileName)
+				if err := m.todoList.UpdateRecurrenceRule(m.editingTaskID, m.tempRule); err != nil {
+					m.statusMsg = err.Error()
+					return m, nil
+				}
+				m.save()
 				m.showRecurrenceRuleDialog = false
 				return m, nil
 			}
+
+			// Changing the frequency can hide the focused row
+			rows := m.ruleRows()
+			visible := false
+			for _, row := range rows {
+				if row == m.ruleFocus {
+					visible = true
+				}
+			}
+			if !visible {
+				m.ruleFocus = ruleRowFreq
+			}
 		} else {
 
 			// all actions on key
-
-			switch msg.String() {
-			case "ctrl+c", "q", "esc":
+			k := m.keys.Grid
+			switch {
+			case key.Matches(msg, k.Quit):
 				return m, tea.Quit
 
-			case "left", "h":
+			case key.Matches(msg, k.Help):
+				m.showHelp = true
+				return m, nil
+
+			case key.Matches(msg, k.Trash):
+				m.showTrash = true
+				m.trashIdx = 0
+				return m, nil
+
+			case key.Matches(msg, k.Logbook):
+				archive, err := todo.LoadArchive(m.store)
+				if err != nil {
+					m.statusMsg = "Could not read the archive: " + err.Error()
+					return m, nil
+				}
+				m.logbook = todo.Logbook(archive, *m.todoList, time.Time{})
+				m.logbookScroll = 0
+				m.showLogbook = true
+				return m, nil
+
+			case key.Matches(msg, k.Undo):
+				m.stepHistory(m.history.Undo, "Undid")
+				return m, nil
+
+			case key.Matches(msg, k.Redo):
+				m.stepHistory(m.history.Redo, "Redid")
+				return m, nil
+
+			case key.Matches(msg, k.Left):
 				if m.cursorDay > 0 {
 					m.cursorDay--
 					m.cursorIdx = 0
 				}
-			case "right", "l":
+			case key.Matches(msg, k.Right):
 				if m.cursorDay < 7 {
 					m.cursorDay++
 					m.cursorIdx = 0
 				}
-			case "up", "k":
+			case key.Matches(msg, k.Up):
 				if m.cursorIdx > 0 {
 					m.cursorIdx--
 				}
-			case "down", "j":
+			case key.Matches(msg, k.Down):
 				if m.cursorIdx < len(tasks)-1 {
 					m.cursorIdx++
 				}
-			case "n":
+			case key.Matches(msg, k.New):
 				if !m.showNewTask {
 					m.showNewTask = true
 					m.textInput.Focus()
 					return m, nil
 				}
-			case "x", "delete", "backspace":
+			case key.Matches(msg, k.Delete):
 				m.showConfirmDeleteDialog = true
 
-			case "e": // go to edit
+			case key.Matches(msg, k.Edit): // go to edit
 				tasks := m.getTasksForDay(m.cursorDay)
 				if len(tasks) > 0 && m.cursorIdx < len(tasks) {
 					selected := tasks[m.cursorIdx]
 
 					m.editingTaskID = selected.ID
+					m.editingDay, m.editingOriginal = selected.Day, selected.Original
 
 					m.textInput.SetValue(selected.Task)
 					m.noteInput.SetValue(selected.Notes)
@@ -792,14 +1343,15 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 					return m, nil
 				}
-			case "r": // Open Recurrence Settings
+			case key.Matches(msg, k.Recurrence): // Open Recurrence Settings
 				tasks := m.getTasksForDay(m.cursorDay)
 				if len(tasks) > 0 {
 					selected := tasks[m.cursorIdx]
 					m.editingTaskID = selected.ID
+					m.editingDay, m.editingOriginal = selected.Day, selected.Original
 
 					if selected.RecurrenceRule != nil {
-						m.tempRule = *selected.RecurrenceRule // Copy existing
+						m.tempRule = selected.RecurrenceRule.Clone() // Copy existing
 					} else {
 						// Create a default new rule
 						m.tempRule = todo.RecurrenceRule{
@@ -812,68 +1364,45 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 
 					m.showRecurrenceRuleDialog = true
-					m.ruleFocus = 0 // Start focus on Frequency
+					m.ruleFocus = ruleRowFreq // Start focus on Frequency
 				}
-			case "m":
+			case key.Matches(msg, k.Move):
 				tasks := m.getTasksForDay(m.cursorDay)
 				if len(tasks) > 0 && m.cursorIdx < len(tasks) {
 					selected := tasks[m.cursorIdx]
 
 					m.editingTaskID = selected.ID
+					m.editingDay, m.editingOriginal = selected.Day, selected.Original
 
 					m.showMoveDialog = true
 
 					return m, nil
 				}
 
-			case " ": // Toggle Task Done
+			case key.Matches(msg, k.Toggle):
 				tasks := m.getTasksForDay(m.cursorDay)
 				if len(tasks) > 0 && m.cursorIdx < len(tasks) {
 					selectedTask := tasks[m.cursorIdx]
-					targetID := selectedTask.ID
-
-					// Calculate the date string for the current day being toggled
-					// This is important because we need to know WHICH day of the recurring task we are finishing
-					targetDateStr := m.weekStart.AddDate(0, 0, m.cursorDay).Format("2006-01-02")
-
-					for i := range *m.todoList {
-						if (*m.todoList)[i].ID == targetID {
-							item := &(*m.todoList)[i]
-
-							// --- CASE 1: Recurring Task ---
-							if item.RecurrenceRule != nil {
-								// Check if this date is already in the DoneList
-								foundIdx := -1
-								for idx, date := range item.RecurrenceRule.DoneList {
-									if date == targetDateStr {
-										foundIdx = idx
-										break
-									}
-								}
-
-								if foundIdx != -1 {
-									// Date found: Uncheck it (Remove from list)
-									item.RecurrenceRule.DoneList = append(
-										item.RecurrenceRule.DoneList[:foundIdx],
-										item.RecurrenceRule.DoneList[foundIdx+1:]...,
-									)
-								} else {
-									// Date not found: Check it (Add to list)
-									item.RecurrenceRule.DoneList = append(item.RecurrenceRule.DoneList, targetDateStr)
-								}
-
-							} else {
-								// --- CASE 2: One-time Task ---
-								item.Done = !item.Done
-							}
-
-							// Save immediately to persist the change
-							m.todoList.Save(env.TodoFileName)
-							break
+
+					// The occurrence carries the day being toggled, this is important because
+					// we need to know WHICH day of the recurring task we are finishing
+					occ, err := m.todoList.ToggleOccurrence(selectedTask.ID, selectedTask.Original)
+					if err != nil {
+						m.statusMsg = err.Error()
+						return m, nil
+					}
+
+					// Repeat-after-completion tasks move their next date when checked off
+					if occ.RecurrenceRule != nil && occ.RecurrenceRule.AfterCompletion && occ.Done {
+						if next, ok := occ.NextOccurrence(time.Now()); ok {
+							m.statusMsg = fmt.Sprintf("%s: next due %s", occ.Task, next.Format("Monday, Jan 02"))
 						}
 					}
+
+					// Save immediately to persist the change
+					m.save()
 				}
-			case "i", "enter": // View task details
+			case key.Matches(msg, k.Details):
 				tasks := m.getTasksForDay(m.cursorDay)
 				if len(tasks) > 0 && m.cursorIdx < len(tasks) {
 
@@ -884,9 +1413,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 				}
 
-			case "[":
+			case key.Matches(msg, k.PrevWeek):
 				m.weekStart = m.weekStart.AddDate(0, 0, -7)
-			case "]":
+			case key.Matches(msg, k.NextWeek):
 				m.weekStart = m.weekStart.AddDate(0, 0, 7)
 			}
 		}
@@ -1005,23 +1534,49 @@ func splitAtVisual(s string, width int) (string, string) {
 
 // ui components
 
+// sized fits a column style to the configured column size.
+func (m Model) sized(style lipgloss.Style) lipgloss.Style {
+	return style.
+		Width(m.columnMaxWidth - 2).
+		MaxWidth(m.columnMaxWidth).
+		Height(m.columnMaxHeight - 2).
+		MaxHeight(m.columnMaxHeight)
+}
+
 func (m Model) renderDay(dayIdx int) string {
 	var dateLabel string
-	style := columnStyle.Width(m.columnMaxWidth - 2).Height(m.columnMaxHeight - 2)
+	style := m.sized(columnStyle)
 
 	if dayIdx == 7 {
 		dateLabel = "SOMEDAY"
 	} else {
 		d := m.weekStart.AddDate(0, 0, dayIdx)
-		dateLabel = d.Format("Monday, Jan 02")
+		dateLabel = d.Format(m.opts.DayFormat)
 		if d.Format("2006-01-02") == time.Now().Format("2006-01-02") {
-			style = todayStyle.Height(m.columnMaxHeight - 2)
+			style = m.sized(todayStyle)
+			if plain {
+				style = style.Border(lipgloss.DoubleBorder())
+			}
 		}
 	}
 
 	// Active column highlight
 	if m.cursorDay == dayIdx {
 		style = style.BorderForeground(SecondaryColor)
+		if plain {
+			style = style.Border(lipgloss.ThickBorder())
+		}
+	}
+	if m.ascii {
+		border := asciiBorder
+		if dayIdx < 7 && m.weekStart.AddDate(0, 0, dayIdx).Format("2006-01-02") == time.Now().Format("2006-01-02") {
+			border.Top, border.Bottom = "=", "="
+		}
+		style = style.Border(border)
+	}
+	up, down, tick, ellipsis := "↑", "↓", "✔", "…"
+	if m.ascii {
+		up, down, tick, ellipsis = "^", "v", "x", "..."
 	}
 
 	tasks := m.getTasksForDay(dayIdx)
@@ -1053,7 +1608,7 @@ func (m Model) renderDay(dayIdx int) string {
 		}
 		//"More tasks up" indicator
 		if start > 0 {
-			taskList.WriteString(lipgloss.NewStyle().Faint(true).Render(fmt.Sprintf("\n  ↑ +%d more", start)))
+			taskList.WriteString(lipgloss.NewStyle().Faint(true).Render(fmt.Sprintf("\n  %s +%d more", up, start)))
 		} else {
 			taskList.WriteString("\n")
 		}
@@ -1063,12 +1618,12 @@ func (m Model) renderDay(dayIdx int) string {
 			t := tasks[i]
 			check := "[ ]"
 			if t.Done {
-				check = "[✔]"
+				check = "[" + tick + "]"
 			}
 			line := fmt.Sprintf("%s %s", check, t.Task)
 
 			//truncate long task names
-			line = runewidth.Truncate(line, m.columnMaxWidth-(2+5), "…")
+			line = runewidth.Truncate(line, m.columnMaxWidth-(2+5), ellipsis)
 
 			if m.cursorDay == dayIdx && m.cursorIdx == i {
 				taskList.WriteString(highlightedTask.Render("> "+line) + "\n")
@@ -1079,7 +1634,7 @@ func (m Model) renderDay(dayIdx int) string {
 
 		//"More tasks below" indicator
 		if end < len(tasks) {
-			taskList.WriteString(lipgloss.NewStyle().Faint(true).Render(fmt.Sprintf("  ↓ +%d more", len(tasks)-end)))
+			taskList.WriteString(lipgloss.NewStyle().Faint(true).Render(fmt.Sprintf("  %s +%d more", down, len(tasks)-end)))
 		}
 	}
 
@@ -1087,6 +1642,50 @@ func (m Model) renderDay(dayIdx int) string {
 	return style.Render(content)
 }
 
+// newTask parses the title typed into the new task dialog, see
+// todo.ParseQuickAdd. Without a ^date the task goes to the selected column.
+func (m Model) newTask() (todo.Item, error) {
+	item, dated, err := todo.ParseQuickAdd(m.textInput.Value(), time.Now(), m.opts.WeekStart)
+	if err != nil || dated {
+		return item, err
+	}
+	if m.cursorDay == 7 {
+		if item.RecurrenceRule != nil {
+			return item, errors.New("Someday tasks can't repeat")
+		}
+		item.IsSomeday = true
+		return item, nil
+	}
+	item.Date = m.weekStart.AddDate(0, 0, m.cursorDay)
+	return item, nil
+}
+
+// quickAddPreview shows what the new task dialog is about to add.
+func (m Model) quickAddPreview() string {
+	if strings.TrimSpace(m.textInput.Value()) == "" {
+		return lipgloss.NewStyle().Foreground(dimColor).Render("^fri !high #tag every week")
+	}
+	item, err := m.newTask()
+	if err != nil {
+		return lipgloss.NewStyle().Foreground(DestructiveColor).Render(err.Error())
+	}
+
+	parts := []string{"Someday"}
+	if !item.IsSomeday {
+		parts[0] = item.Date.Format(m.opts.DayFormat)
+	}
+	if item.RecurrenceRule != nil {
+		parts = append(parts, item.RecurrenceRule.Describe())
+	}
+	if item.Priority != todo.NoPriority {
+		parts = append(parts, item.Priority.String()+" priority")
+	}
+	for _, tag := range item.Tags {
+		parts = append(parts, "#"+tag)
+	}
+	return lipgloss.NewStyle().Foreground(mutedColor).Render(fmt.Sprintf("%q · %s", item.Task, strings.Join(parts, " · ")))
+}
+
 func (m Model) renderNewTaskDialog() string {
 
 	dayName := "Someday"
@@ -1095,7 +1694,7 @@ func (m Model) renderNewTaskDialog() string {
 	}
 
 	activeStyle := lipgloss.NewStyle().Foreground(SecondaryColor).Bold(true)
-	inactiveStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	inactiveStyle := lipgloss.NewStyle().Foreground(dimColor)
 
 	titleLabel := " Title "
 	notesLabel := " Notes "
@@ -1117,11 +1716,12 @@ func (m Model) renderNewTaskDialog() string {
 		"",
 		titleLabel,
 		m.textInput.View(),
+		m.quickAddPreview(),
 		"",
 		notesLabel,
 		m.noteInput.View(),
 		"",
-		footerStyle.MarginTop(1).Render("\nTab: Switch, 󰆓 Enter (on Title): Save, 󰆓 Ctrl+S: Save, 󰜺 Esc: Cancel"),
+		m.help.ShortHelpView(m.keys.Form.ShortHelp()),
 	)
 
 	return dialogBoxStyle.Render(content)
@@ -1131,17 +1731,15 @@ func (m Model) renderEditTaskDialog() string {
 	// 1. Logic for context title
 	dayName := "Someday"
 	if m.cursorDay < 7 {
-		dayName = m.weekStart.AddDate(0, 0, m.cursorDay).Format("Monday, Jan 02")
+		dayName = m.weekStart.AddDate(0, 0, m.cursorDay).Format(m.opts.DayFormat)
 	}
 
 	// 2. Styling
 	activeStyle := lipgloss.NewStyle().Foreground(SecondaryColor).Bold(true)
-	inactiveStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	inactiveStyle := lipgloss.NewStyle().Foreground(dimColor)
 
 	// Different header color for "Edit" vs "Add"
-	headerStyle := lipgloss.NewStyle().
-		Foreground(PrimaryForeground).
-		Background(AccentColor).
+	headerStyle := filled(lipgloss.NewStyle(), AccentColor, PrimaryForeground).
 		Padding(0, 1).
 		MarginBottom(1).
 		Bold(true)
@@ -1173,7 +1771,7 @@ func (m Model) renderEditTaskDialog() string {
 		notesLabel,
 		m.noteInput.View(),
 		"",
-		footerStyle.MarginTop(1).Render("Tab: Switch, 󰆓 Enter/Ctrl+S: Save, 󰜺 Esc: Cancel"),
+		m.help.ShortHelpView(m.keys.Form.ShortHelp()),
 	)
 
 	return dialogBoxStyle.Render(content)
@@ -1181,7 +1779,7 @@ func (m Model) renderEditTaskDialog() string {
 
 func (m Model) renderUpdateRecurrenceRuleDialog() string {
 	// --- 1. FREQUENCY ROW ---
-	freqs := []string{"None", "Daily", "Weekly", "Monthly"}
+	freqs := []string{"None", "Daily", "Weekly", "Monthly", "Yearly"}
 	var freqButtons []string
 	for i, f := range freqs {
 		str := f
@@ -1192,13 +1790,13 @@ func (m Model) renderUpdateRecurrenceRuleDialog() string {
 			style = ruleActiveStyle
 		}
 		// Add a special indicator if this specific row is focused
-		if m.ruleFocus == 0 && int(m.tempRule.Freq) == i {
+		if m.ruleFocus == ruleRowFreq && int(m.tempRule.Freq) == i {
 			str = "▶ " + str
 		}
 		freqButtons = append(freqButtons, style.Render(str))
 	}
 	freqRow := lipgloss.JoinHorizontal(lipgloss.Left, freqButtons...)
-	if m.ruleFocus == 0 {
+	if m.ruleFocus == ruleRowFreq {
 		freqRow = ruleFocusStyle.Render(freqRow)
 	}
 
@@ -1209,22 +1807,146 @@ func (m Model) renderUpdateRecurrenceRuleDialog() string {
 		unit = "weeks"
 	case todo.Monthly:
 		unit = "months"
+	case todo.Yearly:
+		unit = "years"
 	}
 	intervalStr := fmt.Sprintf("Repeat every: [ %d ] %s", m.tempRule.Interval, unit)
-	if m.ruleFocus == 1 {
+	if m.tempRule.AfterCompletion {
+		intervalStr = fmt.Sprintf("Repeat: [ %d ] %s after completion", m.tempRule.Interval, unit)
+	}
+	if m.ruleFocus == ruleRowInterval {
 		intervalStr = pickerActiveStyle.Render(intervalStr)
 	}
 
-	// --- 4. ASSEMBLE ---
-	content := lipgloss.JoinVertical(lipgloss.Left,
+	// --- 2b. SCHEDULE MODE ROW ---
+	modes := []string{"Fixed schedule", "After completion"}
+	var modeButtons []string
+	for i, mode := range modes {
+		str := mode
+		style := ruleInactiveStyle
+		if m.tempRule.AfterCompletion == (i == 1) {
+			style = ruleActiveStyle
+			if m.ruleFocus == ruleRowMode {
+				str = "▶ " + str
+			}
+		}
+		modeButtons = append(modeButtons, style.Render(str))
+	}
+	modeRow := lipgloss.JoinHorizontal(lipgloss.Left, modeButtons...)
+	if m.ruleFocus == ruleRowMode {
+		modeRow = ruleFocusStyle.Render(modeRow)
+	}
+
+	// --- 3. MONTHLY / YEARLY POSITION ROW ---
+	var nthRow string
+	if m.pickingNth() {
+		day := int(m.tempRule.MonthDay)
+		if it := m.editingItem(); day == 0 && it != nil {
+			day = it.Date.Day()
+		}
+
+		var nthButtons []string
+		for _, n := range nthPositions {
+			str := todo.OrdinalName(int(n))
+			if n == 0 {
+				str = fmt.Sprintf("Day %d", day)
+			}
+			style := ruleInactiveStyle
+			if m.tempRule.Nth == n {
+				style = ruleActiveStyle
+				if m.ruleFocus == ruleRowNth {
+					str = "▶ " + str
+				}
+			}
+			nthButtons = append(nthButtons, style.Render(str))
+		}
+		nthRow = lipgloss.JoinHorizontal(lipgloss.Left, nthButtons...)
+		if m.ruleFocus == ruleRowNth {
+			nthRow = ruleFocusStyle.Render(nthRow)
+		}
+	}
+
+	// --- 4. WEEKDAYS ROW ---
+	var weekdayRow string
+	if m.pickingWeekdays() {
+		var dayButtons []string
+		for i := 0; i < 7; i++ {
+			wd := time.Weekday((i + 1) % 7)
+			style := ruleInactiveStyle
+			for _, selected := range m.tempRule.Weekdays {
+				if selected == wd {
+					style = ruleActiveStyle
+					break
+				}
+			}
+			label := wd.String()[:2]
+			if m.ruleFocus == ruleRowWeekdays && m.ruleWeekdayCursor == i {
+				label = "▶" + label
+			}
+			dayButtons = append(dayButtons, style.Render(label))
+		}
+		weekdayRow = lipgloss.JoinHorizontal(lipgloss.Left, dayButtons...)
+		if m.ruleFocus == ruleRowWeekdays {
+			weekdayRow = ruleFocusStyle.Render(weekdayRow)
+		}
+	}
+
+	// --- 5. ENDS ROW ---
+	ends := []string{"Never", "On date", "After"}
+	var endButtons []string
+	for i, e := range ends {
+		str := e
+		style := ruleInactiveStyle
+		if m.ruleEnd() == i {
+			style = ruleActiveStyle
+			if m.ruleFocus == ruleRowEnds {
+				str = "▶ " + str
+			}
+		}
+		endButtons = append(endButtons, style.Render(str))
+	}
+	endRow := lipgloss.JoinHorizontal(lipgloss.Left, endButtons...)
+	if m.ruleFocus == ruleRowEnds {
+		endRow = ruleFocusStyle.Render(endRow)
+	}
+
+	var endValue string
+	switch m.ruleEnd() {
+	case ruleEndUntil:
+		endValue = fmt.Sprintf("Last day: [ %s ]", m.tempRule.Until.Format("Mon, Jan 02 2006"))
+	case ruleEndCount:
+		endValue = fmt.Sprintf("Stop after: [ %d ] occurrences", m.tempRule.Count)
+	}
+	if m.ruleFocus == ruleRowEndValue {
+		endValue = pickerActiveStyle.Render(endValue)
+	}
+
+	// --- 6. ASSEMBLE ---
+	rows := []string{
 		lipgloss.NewStyle().Bold(true).MarginBottom(1).Render("RECURRENCE SETTINGS"),
 		"Frequency:",
 		freqRow,
-		"",
-		intervalStr,
-		"",
-		footerStyle.Render("Tab: Move, ←→↑↓: Change Value, 󰆓 Enter: Save, 󰜺 Esc: Cancel"),
-	)
+	}
+	if m.tempRule.Freq != todo.None {
+		rows = append(rows, "", intervalStr, "", "Schedule:", modeRow)
+		if nthRow != "" {
+			rows = append(rows, "", "On:", nthRow)
+		}
+		if weekdayRow != "" {
+			label := "On:"
+			if nthRow != "" {
+				label = "Weekday:"
+			}
+			rows = append(rows, "", label, weekdayRow)
+		}
+		rows = append(rows, "", "Ends:", endRow)
+		if endValue != "" {
+			rows = append(rows, "", endValue)
+		}
+	}
+	rows = append(rows, "", m.help.ShortHelpView(m.keys.Recurrence.ShortHelp()))
+
+	content := lipgloss.JoinVertical(lipgloss.Left, rows...)
 
 	return dialogBoxStyle.Render(content)
 }
@@ -1233,20 +1955,210 @@ func (m Model) renderMoveTaskDialog() string {
 	content := lipgloss.JoinVertical(lipgloss.Center,
 		lipgloss.NewStyle().Bold(true).Render("MOVE TASK"),
 		"",
-		fmt.Sprintf("Press %s to move to Today", choiceStyle.Render("t")),
-		fmt.Sprintf("Press %s to move to Someday", choiceStyle.Render("s")),
-		fmt.Sprintf("Press %s to choose a Date", choiceStyle.Render("c")),
+		fmt.Sprintf("Press %s to move to Today", choiceKey(m.keys.Move.Today)),
+		fmt.Sprintf("Press %s to move to Someday", choiceKey(m.keys.Move.Someday)),
+		fmt.Sprintf("Press %s to choose a Date", choiceKey(m.keys.Move.Calendar)),
+		"",
+		lipgloss.NewStyle().Faint(true).Render("("+m.keys.Move.Cancel.Help().Key+" to cancel)"),
+	)
+
+	return dialogBoxStyle.Align(lipgloss.Center).Render(content)
+}
+
+func (m Model) renderRolloverDialog() string {
+	overdue := m.todoList.Overdue(time.Now())
+
+	lines := []string{
+		lipgloss.NewStyle().Bold(true).Render("UNFINISHED TASKS"),
+		"",
+		fmt.Sprintf("%d task(s) from past days are not done yet:", len(overdue)),
+		"",
+	}
+
+	const maxListed = 5
+	for i, it := range overdue {
+		if i == maxListed {
+			lines = append(lines, lipgloss.NewStyle().Faint(true).Render(fmt.Sprintf("… and %d more", len(overdue)-maxListed)))
+			break
+		}
+		line := fmt.Sprintf("%s  %s", it.Date.Format("Mon, Jan 02"), it.Task)
+		lines = append(lines, runewidth.Truncate(line, 50, "…"))
+	}
+
+	lines = append(lines,
+		"",
+		fmt.Sprintf("Press %s to move them to today, %s to leave them", choiceKey(m.keys.Rollover.Yes), choiceKey(m.keys.Rollover.No)),
+	)
+
+	return dialogBoxStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+// renderHelp lists the bindings of every mode, generated from the key map
+// so remapped keys show up as they are.
+func (m Model) renderHelp() string {
+	sections := []struct {
+		title string
+		keys  help.KeyMap
+	}{
+		{"Week", m.keys.Grid},
+		{"New and edit task", m.keys.Form},
+		{"Task details", m.keys.Details},
+		{"Move task", m.keys.Move},
+		{"Calendar", m.keys.Calendar},
+		{"Repeat", m.keys.Recurrence},
+		{"Delete", m.keys.Delete},
+		{"Recurring edits", m.keys.Scope},
+		{"Trash", m.keys.Trash},
+		{"Logbook", m.keys.Logbook},
+	}
+
+	h := m.help
+	h.Width = 0 // rows are wrapped below
+	width := min(max(m.terminalW-16, 40), 90)
+
+	lines := []string{lipgloss.NewStyle().Bold(true).Render("KEYS"), ""}
+	for i, sec := range sections {
+		lines = append(lines, lipgloss.NewStyle().Foreground(AccentColor).Bold(true).Render(sec.title))
+		if i == 0 {
+			lines = append(lines, h.FullHelpView(sec.keys.FullHelp()), "")
+			continue
+		}
+		// one wrapped line per mode instead of cutting keys off
+		var row []key.Binding
+		for _, col := range sec.keys.FullHelp() {
+			for _, b := range col {
+				if len(row) > 0 && lipgloss.Width(h.ShortHelpView(append(row, b))) > width {
+					lines = append(lines, h.ShortHelpView(row))
+					row = nil
+				}
+				row = append(row, b)
+			}
+		}
+		lines = append(lines, h.ShortHelpView(row), "")
+	}
+	lines = append(lines, lipgloss.NewStyle().Faint(true).Render("("+m.keys.Help.Close.Help().Key+" to close)"))
+
+	return dialogBoxStyle.UnsetWidth().Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+// logbookLines are the completed tasks grouped by week, newest first.
+func (m Model) logbookLines() []string {
+	perWeek := map[time.Time]int{}
+	for _, c := range m.logbook {
+		perWeek[todo.StartOfWeek(c.Day, m.opts.WeekStart)]++
+	}
+
+	var lines []string
+	var week time.Time
+	for _, c := range m.logbook {
+		if start := todo.StartOfWeek(c.Day, m.opts.WeekStart); !start.Equal(week) {
+			if !week.IsZero() {
+				lines = append(lines, "")
+			}
+			week = start
+			header := fmt.Sprintf("Week of %s · %d done", week.Format("Jan 02, 2006"), perWeek[week])
+			lines = append(lines, lipgloss.NewStyle().Foreground(AccentColor).Bold(true).Render(header))
+		}
+		line := fmt.Sprintf("  ✔ %s  %s", c.Day.Format("Mon 02"), c.Task)
+		lines = append(lines, runewidth.Truncate(line, 50, "…"))
+	}
+	return lines
+}
+
+// logbookHeight is how many logbook lines fit on screen.
+func (m Model) logbookHeight() int {
+	return max(m.terminalH-12, 8)
+}
+
+func (m Model) renderLogbook() string {
+	lines := m.logbookLines()
+
+	body := []string{lipgloss.NewStyle().Bold(true).Render("LOGBOOK"), ""}
+	if len(lines) == 0 {
+		body = append(body, lipgloss.NewStyle().Faint(true).Render("Nothing done yet"))
+	}
+	start := min(m.logbookScroll, max(len(lines)-m.logbookHeight(), 0))
+	end := min(start+m.logbookHeight(), len(lines))
+	if start > 0 {
+		body = append(body, lipgloss.NewStyle().Faint(true).Render(fmt.Sprintf("  ↑ %d more lines", start)))
+	}
+	body = append(body, lines[start:end]...)
+	if end < len(lines) {
+		body = append(body, lipgloss.NewStyle().Faint(true).Render(fmt.Sprintf("  ↓ %d more lines", len(lines)-end)))
+	}
+
+	body = append(body, "", m.help.ShortHelpView(m.keys.Logbook.ShortHelp()))
+	return dialogBoxStyle.Render(lipgloss.JoinVertical(lipgloss.Left, body...))
+}
+
+func (m Model) renderTrash() string {
+	trash := m.todoList.Trash()
+
+	lines := []string{lipgloss.NewStyle().Bold(true).Render("TRASH"), ""}
+	if len(trash) == 0 {
+		lines = append(lines, lipgloss.NewStyle().Faint(true).Render("Nothing in the trash"))
+	}
+
+	const maxVisible = 12
+	start := max(min(m.trashIdx-maxVisible/2, len(trash)-maxVisible), 0)
+	end := min(start+maxVisible, len(trash))
+	if start > 0 {
+		lines = append(lines, lipgloss.NewStyle().Faint(true).Render(fmt.Sprintf("  ↑ +%d more", start)))
+	}
+	for i := start; i < end; i++ {
+		it := trash[i]
+		line := fmt.Sprintf("%-40s %s", runewidth.Truncate(it.Task, 40, "…"), it.DeletedAt.Format("Mon, Jan 02 15:04"))
+		if i == m.trashIdx {
+			lines = append(lines, highlightedTask.Render("> "+line))
+		} else {
+			lines = append(lines, "  "+line)
+		}
+	}
+	if end < len(trash) {
+		lines = append(lines, lipgloss.NewStyle().Faint(true).Render(fmt.Sprintf("  ↓ +%d more", len(trash)-end)))
+	}
+
+	lines = append(lines, "", m.help.ShortHelpView(m.keys.Trash.ShortHelp()))
+	return dialogBoxStyle.UnsetWidth().Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+func (m Model) renderScopeDialog() string {
+	action := "Edit"
+	if m.scopeAction == scopeMove {
+		action = "Move"
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Center,
+		lipgloss.NewStyle().Bold(true).Render(strings.ToUpper(action)+" RECURRING TASK"),
+		"",
+		fmt.Sprintf("Press %s for only this one (%s)", choiceKey(m.keys.Scope.Occurrence), m.editingDay.Format("Mon, Jan 02")),
+		fmt.Sprintf("Press %s for the whole series", choiceKey(m.keys.Scope.Series)),
 		"",
-		lipgloss.NewStyle().Faint(true).Render("(esc to cancel)"),
+		lipgloss.NewStyle().Faint(true).Render("("+m.keys.Scope.Cancel.Help().Key+" to cancel)"),
 	)
 
 	return dialogBoxStyle.Align(lipgloss.Center).Render(content)
 }
 
 func (m Model) renderDeleteTaskConfirmDialog() string {
+	tasks := m.getTasksForDay(m.cursorDay)
+	if m.cursorIdx < len(tasks) && tasks[m.cursorIdx].RecurrenceRule != nil && !tasks[m.cursorIdx].Day.IsZero() {
+		content := lipgloss.JoinVertical(lipgloss.Center,
+			lipgloss.NewStyle().Bold(true).Foreground(DestructiveColor).Render("󰆴 DELETE RECURRING TASK"),
+			"",
+			fmt.Sprintf("Press %s to skip this occurrence", choiceKey(m.keys.Delete.Occurrence)),
+			fmt.Sprintf("Press %s to delete this and following", choiceKey(m.keys.Delete.Following)),
+			fmt.Sprintf("Press %s to delete the whole series", choiceKey(m.keys.Delete.Series)),
+			"",
+			lipgloss.NewStyle().Faint(true).Render("("+m.keys.Delete.Cancel.Help().Key+" to cancel)"),
+		)
+
+		return deleteBoxStyle.Render(content)
+	}
+
 	content := lipgloss.JoinVertical(lipgloss.Center,
-		lipgloss.NewStyle().Bold(true).Foreground(DestructiveColor).Render("󰆴 Enter to Delete Task"),
-		lipgloss.NewStyle().Bold(true).Render("󰜺 Esc to Cancel"),
+		lipgloss.NewStyle().Bold(true).Foreground(DestructiveColor).Render("󰆴 "+m.keys.Delete.Occurrence.Help().Key+" to Delete Task"),
+		lipgloss.NewStyle().Bold(true).Render("󰜺 "+m.keys.Delete.Cancel.Help().Key+" to Cancel"),
 	)
 
 	return deleteBoxStyle.Render(content)
@@ -1269,7 +2181,7 @@ func (m Model) renderTaskDetails() string {
 		status = lipgloss.NewStyle().Foreground(SecondaryColor).Render("Completed ✔")
 	}
 
-	dateStr := t.Date.Format("Monday, Jan 02, 2006")
+	dateStr := t.Date.Format(m.opts.DateFormat)
 	if t.IsSomeday {
 		dateStr = lipgloss.NewStyle().Foreground(AccentColor).Render("Someday Drawer")
 	}
@@ -1279,6 +2191,25 @@ func (m Model) renderTaskDetails() string {
 		fmt.Sprintf("%s %s", labelStyle.Render("Status:"), status),
 		fmt.Sprintf("%s %s", labelStyle.Render("Scheduled:"), dateStr),
 	)
+	if t.Priority != todo.NoPriority {
+		metaRows = lipgloss.JoinVertical(lipgloss.Left,
+			metaRows,
+			fmt.Sprintf("%s %s", labelStyle.Render("Priority:"), t.Priority),
+		)
+	}
+	if len(t.Tags) > 0 {
+		metaRows = lipgloss.JoinVertical(lipgloss.Left,
+			metaRows,
+			fmt.Sprintf("%s #%s", labelStyle.Render("Tags:"), strings.Join(t.Tags, " #")),
+		)
+	}
+	if t.Rollovers > 0 && t.OriginalDate != nil {
+		slipped := fmt.Sprintf("%d time(s), first planned for %s", t.Rollovers, t.OriginalDate.Format("Jan 02, 2006"))
+		metaRows = lipgloss.JoinVertical(lipgloss.Left,
+			metaRows,
+			fmt.Sprintf("%s %s", labelStyle.Render("Slipped:"), lipgloss.NewStyle().Foreground(AccentColor).Render(slipped)),
+		)
+	}
 
 	// 3. Notes Section
 	notesTitle := labelStyle.Render("Notes:")
@@ -1290,7 +2221,7 @@ func (m Model) renderTaskDetails() string {
 
 	// 4. Footer hints
 	footer := footerStyle.MarginTop(1).
-		Render("\n e to edit, 󰜺 Esc to close")
+		Render("\n " + m.keys.Details.Edit.Help().Key + " to edit, 󰜺 " + m.keys.Details.Close.Help().Key + " to close")
 
 	// Assemble everything
 	content := lipgloss.JoinVertical(lipgloss.Left,
@@ -1311,16 +2242,16 @@ func (m Model) renderMoveTaskDatePicker() string {
 	// Header: "October 2023"
 	header := monthHeaderStyle.Render(fmt.Sprintf("%s %d", m.pickerMonth.String(), m.pickerYear))
 
-	// Weekday Headers: Su Mo Tu We Th Fr Sa
+	// Weekday Headers: Su Mo Tu We Th Fr Sa, starting on the configured day
 	daysOfWeek := []string{"Su", "Mo", "Tu", "We", "Th", "Fr", "Sa"}
 	var dayHeader strings.Builder
-	for _, d := range daysOfWeek {
-		dayHeader.WriteString(weekdayStyle.Render(d))
+	for i := range daysOfWeek {
+		dayHeader.WriteString(weekdayStyle.Render(daysOfWeek[(i+int(m.opts.WeekStart))%7]))
 	}
 
 	// Calculate Month Grid
 	firstDay := time.Date(m.pickerYear, m.pickerMonth, 1, 0, 0, 0, 0, time.Local)
-	startOffset := int(firstDay.Weekday())
+	startOffset := (int(firstDay.Weekday()) - int(m.opts.WeekStart) + 7) % 7
 	daysInMonth := time.Date(m.pickerYear, m.pickerMonth+1, 0, 0, 0, 0, 0, time.Local).Day()
 
 	var calendar strings.Builder
@@ -1351,9 +2282,15 @@ func (m Model) renderMoveTaskDatePicker() string {
 		}
 	}
 
-	footer := footerStyle.MarginTop(1).Render(
-		"←→↑↓: Nav, [:Pre Month, ]: Next Month, PgDn:Pre Year,PgUp: Next Year\nEnter: Pick, Esc: Close",
-	)
+	footer := lipgloss.NewStyle().MarginTop(1).Render(m.help.FullHelpView(m.keys.Calendar.FullHelp()))
+	if m.pickerJump {
+		jump := []string{"Jump to: " + m.jumpInput.View()}
+		if m.jumpErr != "" {
+			jump = append(jump, lipgloss.NewStyle().Foreground(DestructiveColor).Width(34).Render(m.jumpErr))
+		}
+		jump = append(jump, fmt.Sprintf("%s jump, %s back", choiceKey(m.keys.Form.Submit), choiceKey(m.keys.Form.Cancel)))
+		footer = lipgloss.NewStyle().MarginTop(1).Render(lipgloss.JoinVertical(lipgloss.Left, jump...))
+	}
 
 	return calendarBoxStyle.Render(lipgloss.JoinVertical(lipgloss.Left,
 		header,
@@ -1365,22 +2302,25 @@ func (m Model) renderMoveTaskDatePicker() string {
 
 //---------------------------------------------------------------------------------------------------------------------------------
 
-func (m Model) View() string {
-	// Header
+// renderHeader is the app name and the range of the week shown.
+func (m Model) renderHeader() string {
 	weekRange := fmt.Sprintf(" %s - %s ",
 		m.weekStart.Format("Jan 02"),
 		m.weekStart.AddDate(0, 0, 6).Format("Jan 02, 2006"))
-	header := headerStyle.Render(env.AppName) + "  " + weekRange
+	return headerStyle.Render(env.AppName) + "  " + weekRange
+}
 
-	// grid
+// renderGrid lays the day columns and Someday out in rows that fit the
+// terminal width.
+func (m Model) renderGrid() string {
 	unitWidth := m.columnMaxWidth
 
 	colsPerRow := m.terminalW / unitWidth
 	if colsPerRow <= 2 {
-		m.columnMaxHeight = 14
+		m.columnMaxHeight = min(m.opts.ColumnHeight, 14)
 		colsPerRow = 2
 	} else {
-		m.columnMaxHeight = 19
+		m.columnMaxHeight = m.opts.ColumnHeight
 	}
 	if colsPerRow > 8 {
 		colsPerRow = 8
@@ -1399,11 +2339,22 @@ func (m Model) View() string {
 		}
 	}
 
-	grid := lipgloss.JoinVertical(lipgloss.Left, rows...)
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
+
+func (m Model) View() string {
+	header := m.renderHeader()
+	grid := m.renderGrid()
 
 	// Footer
-	helpText := "• ← →: Day, ↑ ↓: Task, Space: Toggle, n:  Add Task, e:  Edit Task, m:  Move task, r:  Recurrence Setting, Delete/x: 󰆴 Delete task, [: Prev Week, ]: Next Week, Esc/q: Quit •"
+	helpText := m.help.ShortHelpView(m.activeKeys().ShortHelp())
 	footer := footerStyle.Width(m.terminalW).MarginTop(1).Render(helpText)
+	if m.statusMsg != "" {
+		footer = lipgloss.JoinVertical(lipgloss.Left,
+			lipgloss.NewStyle().Foreground(AccentColor).Bold(true).MarginTop(1).Render(" "+m.statusMsg),
+			footerStyle.Width(m.terminalW).Render(helpText),
+		)
+	}
 
 	mainView := lipgloss.JoinVertical(lipgloss.Left, header, grid, footer)
 
@@ -1411,7 +2362,63 @@ func (m Model) View() string {
 	bgWidth := lipgloss.Width(dimmedBG)
 	bgHeight := lipgloss.Height(dimmedBG)
 
-	if m.showNewTask {
+	if m.showRolloverDialog {
+
+		dialog := m.renderRolloverDialog()
+
+		// Calculate the center position
+		fgWidth := lipgloss.Width(dialog)
+		fgHeight := lipgloss.Height(dialog)
+
+		// Calculate top-left corner for the dialog to be centered
+		x := (bgWidth - fgWidth) / 2
+		y := (bgHeight - fgHeight) / 2
+
+		// Return the overlaid result
+		return overlay(dimmedBG, dialog, x, y)
+	} else if m.showHelp {
+
+		dialog := m.renderHelp()
+
+		// Calculate the center position
+		fgWidth := lipgloss.Width(dialog)
+		fgHeight := lipgloss.Height(dialog)
+
+		// Calculate top-left corner for the dialog to be centered
+		x := (bgWidth - fgWidth) / 2
+		y := (bgHeight - fgHeight) / 2
+
+		// Return the overlaid result
+		return overlay(dimmedBG, dialog, x, y)
+	} else if m.showTrash {
+
+		dialog := m.renderTrash()
+
+		// Calculate the center position
+		fgWidth := lipgloss.Width(dialog)
+		fgHeight := lipgloss.Height(dialog)
+
+		// Calculate top-left corner for the dialog to be centered
+		x := (bgWidth - fgWidth) / 2
+		y := (bgHeight - fgHeight) / 2
+
+		// Return the overlaid result
+		return overlay(dimmedBG, dialog, x, y)
+	} else if m.showLogbook {
+
+		dialog := m.renderLogbook()
+
+		// Calculate the center position
+		fgWidth := lipgloss.Width(dialog)
+		fgHeight := lipgloss.Height(dialog)
+
+		// Calculate top-left corner for the dialog to be centered
+		x := (bgWidth - fgWidth) / 2
+		y := (bgHeight - fgHeight) / 2
+
+		// Return the overlaid result
+		return overlay(dimmedBG, dialog, x, y)
+	} else if m.showNewTask {
 
 		dialog := m.renderNewTaskDialog()
 
@@ -1495,6 +2502,19 @@ func (m Model) View() string {
 
 		// Return the overlaid result
 		return overlay(dimmedBG, taskMoveDialog, x, y)
+	} else if m.showScopeDialog {
+		scopeDialog := m.renderScopeDialog()
+
+		// Calculate the center position
+		fgWidth := lipgloss.Width(scopeDialog)
+		fgHeight := lipgloss.Height(scopeDialog)
+
+		// Calculate top-left corner for the dialog to be centered
+		x := (bgWidth - fgWidth) / 2
+		y := (bgHeight - fgHeight) / 2
+
+		// Return the overlaid result
+		return overlay(dimmedBG, scopeDialog, x, y)
 	} else if m.showRecurrenceRuleDialog {
 		taskMoveDialog := m.renderUpdateRecurrenceRuleDialog()
 
@@ -0,0 +1,33 @@
+package tui
+
+import (
+	"time"
+	"weektcli/internal/todo"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// asciiBorder draws columns without box-drawing characters.
+var asciiBorder = lipgloss.Border{
+	Top: "-", Bottom: "-", Left: "|", Right: "|",
+	TopLeft: "+", TopRight: "+", BottomLeft: "+", BottomRight: "+",
+}
+
+// RenderWeek draws the week offset weeks away from this one the way the
+// TUI shows it, without the cursor, for printing outside the alt screen.
+// The columns wrap to fit width. With ascii set the borders and marks use
+// plain ASCII, for files and terminals without Unicode; colors are up to
+// the lipgloss color profile.
+func RenderWeek(l *todo.List, opts Options, offset, width int, ascii bool) string {
+	m := Model{
+		todoList:        l,
+		weekStart:       todo.StartOfWeek(time.Now(), opts.WeekStart).AddDate(0, 0, 7*offset),
+		cursorDay:       -1,
+		terminalW:       width,
+		opts:            opts,
+		ascii:           ascii,
+		columnMaxWidth:  opts.ColumnWidth,
+		columnMaxHeight: opts.ColumnHeight,
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, m.renderHeader(), m.renderGrid())
+}
@@ -1,131 +1,1685 @@
 package main
 
 import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"text/template"
 	"weektcli/env"
+	"weektcli/internal/config"
 	"weektcli/internal/todo"
 	"weektcli/internal/tui"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
 	"github.com/google/uuid"
+	"github.com/muesli/termenv"
 	"github.com/spf13/cobra"
 )
 
 var todoList todo.List
 
+// baseList is the list as loaded, saveList only writes what changed since
+// so edits another process saved in the meantime survive.
+var baseList todo.List
+
+var store todo.Store
+
+// windowed is set when todoList holds only some days, see loadWindow.
+var windowed bool
+
+// history is the undo history of the store, shared with the TUI.
+var history *todo.History
+
+// listingFile keeps the rows of the last `weektcli list`, so other commands
+// can refer to tasks by their number.
+var listingFile string
+
+// cfg holds the settings from the config file.
+var cfg config.Config
+
 func main() {
-	todoList.Load(env.TodoFileName)
+	var storeKind, dataFile string
+	var rootCmd = &cobra.Command{
+		Use: "weektcli",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			loadConfig()
+
+			// Flags win over environment variables, which win over the config file
+			if !cmd.Flags().Changed("store") && os.Getenv("WEEKTCLI_STORE") == "" {
+				storeKind = cfg.Store
+			}
+			if !cmd.Flags().Changed("file") && env.DataFile == "" {
+				dataFile = cfg.DataFile
+			}
+			if os.Getenv("WEEKTCLI_ROLLOVER") == "" {
+				env.RolloverMode = cfg.Rollover
+			}
 
-	var rootCmd = &cobra.Command{Use: "weektcli"}
+			openStore(storeKind, dataFile)
+			// Commands that only show some days load those themselves, see
+			// loadWindow, and commands about the store itself load nothing
+			load := cmd.Annotations["load"]
+			if load == "window" || load == "none" {
+				return
+			}
+			setList(store.Load())
+			// Emptying the trash and archiving write the data file, which
+			// commands that only read leave to the next command that writes
+			if load == "read" {
+				return
+			}
+			purgeExpiredTrash()
+			archiveCompleted()
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			store.Close()
+		},
+	}
+	rootCmd.PersistentFlags().StringVar(&storeKind, "store", env.StoreKind, "Storage backend: "+strings.Join(todo.StoreKinds, " or "))
+	rootCmd.PersistentFlags().StringVar(&dataFile, "file", env.DataFile, "Data file to use instead of the one in the data directory")
 
 	// --- EXISTING ADD COMMAND ---
-	var someday bool
-	var dateStr string
+	var someday, raw bool
+	var dateStr, rruleStr string
 	var addCmd = &cobra.Command{
 		Use:   "add [task]",
 		Short: "Add a task to a day or Someday",
-		Args:  cobra.MinimumNArgs(1),
+		Long: "Add a task to a day or Someday. The title can carry quick-add markers:\n" +
+			"  ^<date>    the day, " + todo.DateHelp + "\n" +
+			"  !<level>   the priority: !low, !medium or !high\n" +
+			"  #<tag>     a tag\n" +
+			"  every ...  every day, every 2 weeks, every other month, every weekday, every mon,thu\n\n" +
+			"e.g. weektcli add Pay rent ^1st !high #home every month",
+		Args: cobra.MinimumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			var taskDate time.Time
+			title := strings.Join(args, " ")
+			item, dated := todo.Item{Task: title}, false
+			if !raw {
+				var err error
+				if item, dated, err = todo.ParseQuickAdd(title, time.Now(), cfg.WeekStartDay()); err != nil {
+					fmt.Println("Error:", err)
+					return
+				}
+			}
+			quick := dated || item.RecurrenceRule != nil || item.Priority != todo.NoPriority || len(item.Tags) > 0
+
 			if dateStr != "" {
-				parsedDate, _ := time.Parse("2006-01-02", dateStr)
-				taskDate = parsedDate
-			} else {
-				taskDate = time.Now()
+				if dated {
+					fmt.Println("Error: --date and a ^date in the title exclude each other")
+					return
+				}
+				var err error
+				if item.Date, item.IsSomeday, err = todo.ParseDate(dateStr, time.Now(), cfg.WeekStartDay()); err != nil {
+					fmt.Println("Error:", err)
+					return
+				}
+				dated = true
+			}
+			if !dated {
+				item.Date = time.Now()
+			}
+			item.IsSomeday = item.IsSomeday || someday
+
+			if rruleStr != "" {
+				if item.RecurrenceRule != nil {
+					fmt.Println("Error: --rrule and an every ... in the title exclude each other")
+					return
+				}
+				rule, err := todo.ParseRRule(rruleStr, item.Date)
+				if err != nil {
+					fmt.Println("Error:", err)
+					return
+				}
+				if rule.Freq != todo.None {
+					item.RecurrenceRule = &rule
+				}
+			}
+			if item.IsSomeday && item.RecurrenceRule != nil {
+				fmt.Println("Error: Someday tasks can't repeat")
+				return
+			}
+
+			todoList.AddItem(item)
+			saveList()
+			if !quick {
+				fmt.Printf("Added: %s\n", item.Task)
+				return
+			}
+
+			details := []string{"someday"}
+			if !item.IsSomeday {
+				details[0] = item.Date.Format("Mon 2006-01-02")
+			}
+			if item.RecurrenceRule != nil {
+				details = append(details, item.RecurrenceRule.Describe())
+			}
+			if item.Priority != todo.NoPriority {
+				details = append(details, item.Priority.String()+" priority")
+			}
+			for _, tag := range item.Tags {
+				details = append(details, "#"+tag)
 			}
-			todoList.Add(args[0], "", taskDate, someday)
-			todoList.Save(env.TodoFileName)
-			fmt.Printf("Added: %s\n", args[0])
+			fmt.Printf("Added: %s (%s)\n", item.Task, strings.Join(details, ", "))
 		},
 	}
 	addCmd.Flags().BoolVarP(&someday, "someday", "s", false, "Add to Someday list")
-	addCmd.Flags().StringVarP(&dateStr, "date", "d", "", "Specific date (YYYY-MM-DD)")
+	addCmd.Flags().StringVarP(&dateStr, "date", "d", "", "Day of the task, e.g. tomorrow, fri, in 3 days or YYYY-MM-DD")
+	addCmd.Flags().StringVar(&rruleStr, "rrule", "", "Repeat using an RFC 5545 RRULE (e.g. FREQ=WEEKLY;BYDAY=MO,WE)")
+	addCmd.Flags().BoolVar(&raw, "raw", false, "Take the title as it is, without quick-add markers")
 
 	// --- NEW: DELETE COMMAND ---
+	var deleteDateStr string
+	var following bool
 	var deleteCmd = &cobra.Command{
-		Use:   "delete [id]",
-		Short: "Delete a task by ID",
-		Args:  cobra.ExactArgs(1),
+		Use:   "delete [task]",
+		Short: "Delete a task",
+		Long: "Delete a task. For recurring tasks --date, or a number from the last list,\n" +
+			"removes a single occurrence, add --following to also remove every later one.\n\n" + taskRefHelp,
+		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			err := todoList.DeleteTask(env.TodoFileName, args[0])
+			task, day, err := resolve(todoList.Active(), args[0])
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if task.RecurrenceRule == nil {
+				day = time.Time{}
+			}
+
+			if deleteDateStr == "" && day.IsZero() {
+				if following {
+					fmt.Println("Error: --following needs --date")
+					return
+				}
+				if err := todoList.DeleteTask(task.ID.String()); err != nil {
+					fmt.Println("Error:", err)
+					return
+				}
+				saveList()
+				fmt.Println("Task moved to the trash.")
+				return
+			}
+
+			if deleteDateStr != "" {
+				day, err = parseDay(deleteDateStr)
+				if err == nil {
+					day, err = originalOn(task, day)
+				}
+				if err != nil {
+					fmt.Println("Error:", err)
+					return
+				}
+			}
+
+			if following {
+				err = todoList.EndSeriesBefore(task.ID, day)
+			} else {
+				err = todoList.SkipOccurrence(task.ID, day)
+			}
 			if err != nil {
 				fmt.Println("Error:", err)
 				return
 			}
-			fmt.Println("Task deleted.")
+			saveList()
+
+			if it, _ := todoList.GetTaskDetails(task.ID.String()); it.Trashed() {
+				fmt.Println("Task moved to the trash.")
+			} else if following {
+				fmt.Printf("Occurrences from %s on deleted.\n", day.Format(todo.DateLayout))
+			} else {
+				fmt.Printf("Occurrence on %s skipped.\n", day.Format(todo.DateLayout))
+			}
 		},
 	}
+	deleteCmd.Flags().StringVarP(&deleteDateStr, "date", "d", "", "Only delete the occurrence on this day, e.g. fri or YYYY-MM-DD")
+	deleteCmd.Flags().BoolVar(&following, "following", false, "With --date, also delete every later occurrence")
 
 	// --- NEW: TOGGLE COMMAND ---
+	var toggleDateStr string
 	var toggleCmd = &cobra.Command{
-		Use:   "toggle [id]",
+		Use:   "toggle [task]",
 		Short: "Toggle task done/undone",
-		Args:  cobra.ExactArgs(1),
+		Long: "Toggle a task done or undone. A number from the last list toggles the\n" +
+			"occurrence on the listed day.\n\n" + taskRefHelp,
+		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			// Logic: Find task, flip boolean, save
-			found := false
-			for i := range todoList {
-				if todoList[i].ID.String() == args[0] {
-					todoList[i].Done = !todoList[i].Done
-					found = true
-					break
+			// Logic: Find task, flip the occurrence for the given day, save
+			task, day, err := resolve(todoList.Active(), args[0])
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			if day.IsZero() || toggleDateStr != "" {
+				day = time.Now()
+				if toggleDateStr != "" {
+					day, err = parseDay(toggleDateStr)
+				}
+				if err == nil {
+					day, err = originalOn(task, day)
+				}
+				if err != nil {
+					fmt.Println("Error:", err)
+					return
 				}
 			}
-			if found {
-				todoList.Save(env.TodoFileName)
-				fmt.Println("Task status toggled.")
+
+			occ, err := todoList.ToggleOccurrence(task.ID, day)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			saveList()
+
+			if occ.RecurrenceRule != nil {
+				fmt.Printf("Task status toggled for %s.\n", occ.Day.Format(todo.DateLayout))
 			} else {
-				fmt.Println("Task not found.")
+				fmt.Println("Task status toggled.")
+			}
+
+			if occ.RecurrenceRule != nil && occ.RecurrenceRule.AfterCompletion {
+				if next, ok := occ.NextOccurrence(time.Now()); ok {
+					fmt.Printf("Next due: %s\n", next.Format(todo.DateLayout))
+				} else {
+					fmt.Println("No occurrences left.")
+				}
 			}
 		},
 	}
+	toggleCmd.Flags().StringVarP(&toggleDateStr, "date", "d", "", "Occurrence of a recurring task to toggle, or completion day for after-completion tasks (e.g. yesterday or YYYY-MM-DD, default today)")
 
 	// --- NEW: EDIT COMMAND ---
-	var notes string
+	var notes, editDateStr string
 	var editCmd = &cobra.Command{
-		Use:   "edit [id] [new title]",
+		Use:   "edit [task] [new title]",
 		Short: "Edit a task title and notes",
-		Args:  cobra.MinimumNArgs(2),
+		Long: "Edit a task title and notes. For recurring tasks --date, or a number from\n" +
+			"the last list, edits a single occurrence.\n\n" + taskRefHelp,
+		Args: cobra.MinimumNArgs(2),
 		Run: func(cmd *cobra.Command, args []string) {
-			todoList.UpdateTask(uuid.MustParse(args[0]), args[1], notes)
-			todoList.Save(env.TodoFileName)
+			task, day, err := resolve(todoList.Active(), args[0])
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if task.RecurrenceRule == nil {
+				day = time.Time{}
+			}
+
+			if editDateStr != "" || !day.IsZero() {
+				if editDateStr != "" {
+					day, err = parseDay(editDateStr)
+					if err == nil {
+						day, err = originalOn(task, day)
+					}
+					if err != nil {
+						fmt.Println("Error:", err)
+						return
+					}
+				}
+				if err := todoList.UpdateOccurrence(task.ID, day, args[1], notes); err != nil {
+					fmt.Println("Error:", err)
+					return
+				}
+				saveList()
+				fmt.Printf("Occurrence on %s updated.\n", day.Format(todo.DateLayout))
+				return
+			}
+
+			todoList.UpdateTask(task.ID, args[1], notes)
+			saveList()
 			fmt.Println("Task updated.")
 		},
 	}
 	editCmd.Flags().StringVarP(&notes, "notes", "n", "", "Update notes for the task")
+	editCmd.Flags().StringVarP(&editDateStr, "date", "d", "", "Only edit the occurrence of a recurring task on this day, e.g. fri or YYYY-MM-DD")
+
+	// --- MOVE COMMAND ---
+	var moveDateStr string
+	var moveCmd = &cobra.Command{
+		Use:   "move [task] [date]",
+		Short: "Move a task to another day or to Someday",
+		Long: "Move a task to another day or to Someday. The date is " + todo.DateHelp + ".\n" +
+			"Recurring tasks move as a whole series, --date or a number from the last list\n" +
+			"moves only that occurrence.\n\n" + taskRefHelp,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			task, day, err := resolve(todoList.Active(), args[0])
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if task.RecurrenceRule == nil {
+				day = time.Time{}
+			}
+			target, someday, err := todo.ParseDate(strings.Join(args[1:], " "), time.Now(), cfg.WeekStartDay())
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			if moveDateStr != "" || !day.IsZero() {
+				if moveDateStr != "" {
+					day, err = parseDay(moveDateStr)
+					if err == nil {
+						day, err = originalOn(task, day)
+					}
+				}
+				if err == nil && someday {
+					err = errors.New("a single occurrence can't move to Someday")
+				}
+				if err == nil {
+					err = todoList.MoveOccurrence(task.ID, day, target)
+				}
+				if err != nil {
+					fmt.Println("Error:", err)
+					return
+				}
+				saveList()
+				fmt.Printf("Occurrence on %s moved to %s.\n", day.Format(todo.DateLayout), target.Format(todo.DateLayout))
+				return
+			}
+
+			if someday {
+				if err := todoList.MoveToSomeday(task.ID); err != nil {
+					fmt.Println("Error:", err)
+					return
+				}
+				saveList()
+				fmt.Printf("Moved %q to Someday.\n", task.Task)
+				return
+			}
+			todoList.MoveTask(task.ID, target)
+			saveList()
+			fmt.Printf("Moved %q to %s.\n", task.Task, target.Format("Mon 2006-01-02"))
+		},
+	}
+	moveCmd.Flags().StringVarP(&moveDateStr, "date", "d", "", "Only move the occurrence of a recurring task on this day, e.g. fri or YYYY-MM-DD")
 
 	// --- NEW: DETAILS COMMAND ---
 	var getCmd = &cobra.Command{
-		Use:   "get [id]",
-		Short: "Get full details of a task",
+		Use:         "get [task]",
+		Short:       "Get full details of a task",
+		Long:        "Get full details of a task, also one in the trash.\n\n" + taskRefHelp,
+		Annotations: map[string]string{"load": "read"},
+		Args:        cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			t, _, err := lookup(todoList, args[0])
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			fmt.Printf("ID:      %s\n", t.ID)
+			fmt.Printf("Task:    %s\n", t.Task)
+			fmt.Printf("Done:    %v\n", t.Done)
+			fmt.Printf("Notes:   %s\n", t.Notes)
+			fmt.Printf("Date:    %s\n", t.Date.Format(todo.DateLayout))
+			if t.Priority != todo.NoPriority {
+				fmt.Printf("Prio:    %s\n", t.Priority)
+			}
+			if len(t.Tags) > 0 {
+				fmt.Printf("Tags:    #%s\n", strings.Join(t.Tags, " #"))
+			}
+			if t.Rollovers > 0 && t.OriginalDate != nil {
+				fmt.Printf("Slipped: %d time(s), first planned for %s\n", t.Rollovers, t.OriginalDate.Format(todo.DateLayout))
+			}
+			if t.Trashed() {
+				fmt.Printf("Deleted: %s (in the trash)\n", t.DeletedAt.Format("2006-01-02 15:04"))
+			}
+			if t.RecurrenceRule != nil {
+				fmt.Printf("Repeats: %s\n", t.RecurrenceRule.Describe())
+				if rrule, err := t.RecurrenceRule.RRule(t.Date); err == nil {
+					fmt.Printf("RRULE:   %s\n", rrule)
+				} else {
+					fmt.Printf("RRULE:   none (%v)\n", err)
+				}
+				if len(t.RecurrenceRule.Exceptions) > 0 {
+					fmt.Printf("Skipped: %s\n", strings.Join(t.RecurrenceRule.Exceptions, ", "))
+				}
+				if next, ok := t.NextOccurrence(time.Now()); ok {
+					fmt.Printf("Next:    %s\n", next.Format(todo.DateLayout))
+				}
+			}
+		},
+	}
+
+	// --- LIST COMMAND ---
+	var listWeek int
+	var listFrom, listTo, listFormat, listTemplate string
+	var listSomeday, listDone, listUndone, listRecurring bool
+	var listCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List tasks of a week, a date range or Someday",
+		Long: "List the tasks of this week, or of the week --week weeks away, or of\n" +
+			"--from to --to. Recurring tasks are listed once per occurrence.\n\n" +
+			"Other commands take the numbers of the last list, e.g. weektcli toggle 3.",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{"load": "window"},
+		Run: func(cmd *cobra.Command, args []string) {
+			if listDone && listUndone {
+				fmt.Println("Error: --done and --undone exclude each other")
+				return
+			}
+
+			var occs []todo.Occurrence
+			if listSomeday {
+				// an empty window still has the Someday tasks
+				loadWindow(time.Now(), time.Now().AddDate(0, 0, -1))
+				for _, it := range todoList.Someday() {
+					occs = append(occs, todo.Occurrence{Item: it})
+				}
+			} else {
+				from := todo.StartOfWeek(time.Now(), cfg.WeekStartDay()).AddDate(0, 0, 7*listWeek)
+				to := from.AddDate(0, 0, 6)
+				var err error
+				if listFrom != "" {
+					if from, err = parseDay(listFrom); err != nil {
+						fmt.Println("Error: --from:", err)
+						return
+					}
+					to = from.AddDate(0, 0, 6)
+				}
+				if listTo != "" {
+					if to, err = parseDay(listTo); err != nil {
+						fmt.Println("Error: --to:", err)
+						return
+					}
+				}
+				if to.Before(from) {
+					fmt.Println("Error: --to is before --from")
+					return
+				}
+				loadWindow(from, to)
+				occs = todoList.Occurrences(from, to)
+			}
+
+			var rows []listRow
+			for _, occ := range occs {
+				recurring := occ.RecurrenceRule != nil
+				if (listDone && !occ.Done) || (listUndone && occ.Done) || (listRecurring && !recurring) {
+					continue
+				}
+				rows = append(rows, newListRow(occ, len(rows)+1))
+			}
+
+			format := listFormat
+			if listTemplate != "" {
+				format = "template"
+			}
+			if err := printRows(rows, format, listTemplate); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			saveListing(rows)
+		},
+	}
+	listCmd.Flags().IntVarP(&listWeek, "week", "w", 0, "Week relative to this one, e.g. -1 for last week")
+	listCmd.Flags().StringVar(&listFrom, "from", "", "First day to list, e.g. \"next monday\" or YYYY-MM-DD")
+	listCmd.Flags().StringVar(&listTo, "to", "", "Last day to list, e.g. \"end of month\" or YYYY-MM-DD")
+	listCmd.Flags().BoolVar(&listSomeday, "someday", false, "List the Someday tasks instead")
+	listCmd.Flags().BoolVar(&listDone, "done", false, "Only done tasks")
+	listCmd.Flags().BoolVar(&listUndone, "undone", false, "Only tasks that are not done")
+	listCmd.Flags().BoolVar(&listRecurring, "recurring", false, "Only recurring tasks")
+	listCmd.Flags().StringVarP(&listFormat, "output", "o", "table", "Output format: table, json or csv")
+	listCmd.Flags().StringVar(&listTemplate, "template", "", "Print every task with a Go template, e.g. '{{.Day}} {{.Task}}'")
+
+	// --- LOG COMMAND ---
+	var sinceStr string
+	var logCmd = &cobra.Command{
+		Use:         "log",
+		Short:       "Show what was done, week by week",
+		Long:        "Show the tasks checked off since a day, archived ones included, grouped by week.",
+		Annotations: map[string]string{"load": "read"},
+		Args:        cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			since := todo.StartOfWeek(time.Now(), cfg.WeekStartDay())
+			if sinceStr != "" {
+				var err error
+				if since, err = parseDay(sinceStr); err != nil {
+					fmt.Println("Error: --since:", err)
+					return
+				}
+			}
+
+			archive, err := todo.LoadArchive(store)
+			if err != nil {
+				fmt.Println("Error: reading the archive:", err)
+				return
+			}
+			done := todo.Logbook(archive, todoList, since)
+			if len(done) == 0 {
+				fmt.Printf("Nothing done since %s.\n", since.Format("Mon, Jan 02 2006"))
+				return
+			}
+
+			perWeek := map[time.Time]int{}
+			for _, c := range done {
+				perWeek[todo.StartOfWeek(c.Day, cfg.WeekStartDay())]++
+			}
+			var week time.Time
+			for _, c := range done {
+				if start := todo.StartOfWeek(c.Day, cfg.WeekStartDay()); !start.Equal(week) {
+					if !week.IsZero() {
+						fmt.Println()
+					}
+					week = start
+					fmt.Printf("Week of %s (%d done)\n", week.Format("Mon, Jan 02 2006"), perWeek[week])
+				}
+				fmt.Printf("  %s  %s\n", c.Day.Format("Mon 01-02"), c.Task)
+			}
+		},
+	}
+	logCmd.Flags().StringVar(&sinceStr, "since", "", "First day to show, e.g. \"4 weeks ago\" or YYYY-MM-DD (default the start of this week)")
+
+	// --- AGENDA COMMAND ---
+	var agendaDays int
+	var agendaJSON bool
+	var agendaCmd = &cobra.Command{
+		Use:   "agenda",
+		Short: "Show overdue tasks, today and the next days",
+		Long: "Show the overdue tasks, today's tasks with recurring ones expanded, and the\n" +
+			"next --days days. Short enough for a shell login script. The tasks are numbered\n" +
+			"like in weektcli list, so weektcli toggle 2 checks off the second one.\n\n" +
+			"Overdue are one-time tasks from earlier days and tasks that repeat after\n" +
+			"completion past their due date. Missed occurrences of tasks on a fixed\n" +
+			"schedule are not, the next occurrence takes their place.",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{"load": "window"},
+		Run: func(cmd *cobra.Command, args []string) {
+			if agendaDays < 0 {
+				fmt.Println("Error: --days must not be negative")
+				return
+			}
+			now := time.Now()
+			// from the beginning, for the overdue tasks
+			loadWindow(time.Time{}, now.AddDate(0, 0, agendaDays))
+
+			var a agenda
+			a.Date = now.Format(todo.DateLayout)
+			for _, occ := range todoList.OverdueOccurrences(now) {
+				a.Overdue = append(a.Overdue, newListRow(occ, 0))
+			}
+			for _, occ := range todoList.OccurrencesOn(now) {
+				a.Today = append(a.Today, newListRow(occ, 0))
+				if occ.Done {
+					a.Counts.TodayDone++
+				}
+			}
+			if agendaDays > 0 {
+				for _, occ := range todoList.Occurrences(now.AddDate(0, 0, 1), now.AddDate(0, 0, agendaDays)) {
+					a.Upcoming = append(a.Upcoming, newListRow(occ, 0))
+				}
+			}
+			a.Counts.Overdue, a.Counts.Today, a.Counts.Upcoming = len(a.Overdue), len(a.Today), len(a.Upcoming)
+
+			// number the rows in the order they are shown
+			var rows []listRow
+			for _, section := range [][]listRow{a.Overdue, a.Today, a.Upcoming} {
+				for i := range section {
+					section[i].Index = len(rows) + 1
+					rows = append(rows, section[i])
+				}
+			}
+			// An overdue task that repeats after completion is done today
+			// when checked off, not on the day it was due
+			for i := range a.Overdue {
+				if a.Overdue[i].Recurring {
+					rows[i].Day, rows[i].Original = a.Date, ""
+				}
+			}
+			saveListing(rows)
+
+			if agendaJSON {
+				for _, section := range []*[]listRow{&a.Overdue, &a.Today, &a.Upcoming} {
+					if *section == nil {
+						*section = []listRow{}
+					}
+				}
+				data, err := json.MarshalIndent(a, "", "  ")
+				if err != nil {
+					fmt.Println("Error:", err)
+					return
+				}
+				fmt.Println(string(data))
+				return
+			}
+
+			summary := fmt.Sprintf("%d task(s) today, %d done", a.Counts.Today, a.Counts.TodayDone)
+			if a.Counts.Overdue > 0 {
+				summary += fmt.Sprintf(", %d overdue", a.Counts.Overdue)
+			}
+			fmt.Printf("%s: %s\n", now.Format(cfg.DateFormat), summary)
+
+			printAgenda := func(title string, rows []listRow, withDay bool) {
+				fmt.Printf("\n%s\n", title)
+				if len(rows) == 0 {
+					fmt.Println("  Nothing.")
+				}
+				for _, r := range rows {
+					check, task := "[ ]", r.Task
+					if r.Done {
+						check = "[✔]"
+					}
+					if r.Recurring {
+						task += " ↻"
+					}
+					day := ""
+					if withDay {
+						d, _ := time.ParseInLocation(todo.DateLayout, r.Day, time.Local)
+						day = d.Format("Mon 01-02") + "  "
+					}
+					fmt.Printf("  %2d  %s%s %s\n", r.Index, day, check, task)
+				}
+			}
+			if len(a.Overdue) > 0 {
+				printAgenda(fmt.Sprintf("Overdue (%d)", a.Counts.Overdue), a.Overdue, true)
+			}
+			printAgenda(fmt.Sprintf("Today (%d, %d done)", a.Counts.Today, a.Counts.TodayDone), a.Today, false)
+			if agendaDays > 0 {
+				printAgenda(fmt.Sprintf("Next %d day(s) (%d)", agendaDays, a.Counts.Upcoming), a.Upcoming, true)
+			}
+		},
+	}
+	agendaCmd.Flags().IntVarP(&agendaDays, "days", "n", 3, "How many days after today to show")
+	agendaCmd.Flags().BoolVar(&agendaJSON, "json", false, "Print the agenda as JSON")
+
+	// --- REPEAT COMMAND ---
+	var freqStr, weekdaysStr, untilStr string
+	var interval, monthDay uint8
+	var nth int8
+	var count uint16
+	var afterCompletion bool
+	var repeatCmd = &cobra.Command{
+		Use:   "repeat [task]",
+		Short: "Set how a task repeats and when it stops",
+		Long:  "Set how a task repeats and when it stops.\n\n" + taskRefHelp,
 		Args:  cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			for _, t := range todoList {
-				if t.ID.String() == args[0] {
-					fmt.Printf("ID:      %s\n", t.ID)
-					fmt.Printf("Task:    %s\n", t.Task)
-					fmt.Printf("Done:    %v\n", t.Done)
-					fmt.Printf("Notes:   %s\n", t.Notes)
-					fmt.Printf("Date:    %s\n", t.Date.Format("2006-01-02"))
+			task, _, err := resolve(todoList.Active(), args[0])
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			// Start from the existing rule so single flags can be tweaked
+			rule := todo.RecurrenceRule{Freq: todo.None, Interval: 1}
+			if task.RecurrenceRule != nil {
+				rule = task.RecurrenceRule.Clone()
+			}
+
+			flags := cmd.Flags()
+			if flags.Changed("freq") {
+				if rule.Freq, err = todo.ParseFrequency(freqStr); err != nil {
+					fmt.Println("Error:", err)
+					return
+				}
+			}
+			if flags.Changed("interval") {
+				rule.Interval = interval
+			}
+			if flags.Changed("weekdays") {
+				if rule.Weekdays, err = todo.ParseWeekdays(weekdaysStr); err != nil {
+					fmt.Println("Error:", err)
 					return
 				}
 			}
-			fmt.Println("Task not found.")
+			if flags.Changed("month-day") {
+				rule.MonthDay = monthDay
+			}
+			if flags.Changed("nth") {
+				rule.Nth = nth
+			} else if rule.Freq != todo.Monthly && rule.Freq != todo.Yearly {
+				rule.Nth = 0
+			}
+			// The nth weekday is the task's own weekday unless --weekdays says otherwise
+			if rule.Nth != 0 && len(rule.Weekdays) == 0 {
+				rule.Weekdays = []time.Weekday{task.Date.Weekday()}
+			}
+			// A task stops after a count or on a day, setting one clears the other
+			if flags.Changed("until") && flags.Changed("count") && untilStr != "" && count > 0 {
+				fmt.Println("Error: --until and --count exclude each other")
+				return
+			}
+			if flags.Changed("until") {
+				rule.Until = nil
+				if untilStr != "" {
+					until, err := parseDay(untilStr)
+					if err != nil {
+						fmt.Println("Error: --until:", err)
+						return
+					}
+					rule.Until, rule.Count = &until, 0
+				}
+			}
+			if flags.Changed("count") {
+				rule.Count = count
+				if count > 0 {
+					rule.Until = nil
+				}
+			}
+			if flags.Changed("after-completion") {
+				rule.AfterCompletion = afterCompletion
+			}
+
+			if err := rule.Validate(); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			if err := todoList.UpdateRecurrenceRule(task.ID, rule); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			saveList()
+			fmt.Printf("Task now %s.\n", rule.Describe())
+		},
+	}
+	repeatCmd.Flags().StringVarP(&freqStr, "freq", "f", "", "Frequency: none, daily, weekly, monthly or yearly")
+	repeatCmd.Flags().Uint8VarP(&interval, "interval", "i", 1, "Repeat every N days/weeks/months/years")
+	repeatCmd.Flags().StringVarP(&weekdaysStr, "weekdays", "w", "", "Weekdays for weekly or nth-weekday tasks (e.g. mon,wed,fri)")
+	repeatCmd.Flags().Uint8Var(&monthDay, "month-day", 0, "Day of month for monthly/yearly tasks (0 = task date)")
+	repeatCmd.Flags().Int8Var(&nth, "nth", 0, "Nth weekday of the month for monthly/yearly tasks (1-5, -1 = last), on the task's weekday unless --weekdays is given")
+	repeatCmd.Flags().StringVarP(&untilStr, "until", "u", "", "Last day the task repeats, e.g. \"end of month\" or YYYY-MM-DD (empty to clear), replaces a count")
+	repeatCmd.Flags().Uint16VarP(&count, "count", "c", 0, "Stop after N occurrences (0 = no limit), replaces a last day")
+	repeatCmd.Flags().BoolVar(&afterCompletion, "after-completion", false, "Schedule the next occurrence from the last completion instead of a fixed calendar")
+
+	// --- ROLLOVER COMMAND ---
+	var dryRun bool
+	var rolloverCmd = &cobra.Command{
+		Use:   "rollover",
+		Short: "Move unfinished tasks from past days to today",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			now := time.Now()
+
+			var tasks []todo.Item
+			if dryRun {
+				tasks = todoList.Overdue(now)
+			} else {
+				tasks = todoList.Rollover(now)
+				saveList()
+			}
+
+			for _, t := range tasks {
+				from := t.Date
+				if !dryRun {
+					from = *t.OriginalDate
+				}
+				fmt.Printf("%s  %s  (from %s)\n", t.ID, t.Task, from.Format(todo.DateLayout))
+			}
+
+			if dryRun {
+				fmt.Printf("%d task(s) would roll over to today.\n", len(tasks))
+			} else {
+				fmt.Printf("%d task(s) rolled over to today.\n", len(tasks))
+			}
 		},
 	}
+	rolloverCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Only list the tasks that would roll over")
+
+	// --- MIGRATE COMMAND ---
+	var migrateDryRun bool
+	var migrateCmd = &cobra.Command{
+		Use:         "migrate",
+		Short:       "Upgrade the data file to the current format",
+		Annotations: map[string]string{"load": "none"},
+		Args:        cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if _, ok := store.(*todo.JSONStore); !ok {
+				fmt.Println("The SQLite store always uses the current format, nothing to migrate.")
+				return
+			}
+			path := store.Path()
+
+			version, steps, err := todo.PlanMigration(path)
+			if errors.Is(err, os.ErrNotExist) {
+				fmt.Println("No data file yet, nothing to migrate.")
+				return
+			}
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			fmt.Printf("%s is version %d, current version is %d.\n", path, version, todo.SchemaVersion)
+			if len(steps) == 0 {
+				fmt.Println("Already up to date.")
+				return
+			}
+			for _, step := range steps {
+				fmt.Printf("  -> %d: %s", step.Version, step.Description)
+				if step.Changed > 0 {
+					fmt.Printf(" (%d task(s) changed)", step.Changed)
+				}
+				fmt.Println()
+			}
+
+			if migrateDryRun {
+				fmt.Println("Dry run, nothing was written.")
+				return
+			}
+			if _, err := todo.MigrateFile(path); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			fmt.Printf("Migrated, the previous version is in %s.\n", todo.BackupName(path))
+		},
+	}
+	migrateCmd.Flags().BoolVarP(&migrateDryRun, "dry-run", "n", false, "Only show the migrations that would run")
+
+	// --- WHERE COMMAND ---
+	var whereCmd = &cobra.Command{
+		Use:         "where",
+		Short:       "Show where the tasks are stored",
+		Annotations: map[string]string{"load": "none"},
+		Args:        cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			dir, err := env.DataDir()
+			if err != nil {
+				dir = "unknown (" + err.Error() + ")"
+			}
+
+			fmt.Printf("Store:     %s\n", storeKind)
+			fmt.Printf("Data file: %s\n", store.Path())
+			if _, ok := store.(*todo.JSONStore); ok {
+				fmt.Printf("Backup:    %s\n", todo.BackupName(store.Path()))
+				fmt.Printf("Lock file: %s\n", todo.LockName(store.Path()))
+			}
+			fmt.Printf("History:   %s\n", history.Path())
+			fmt.Printf("Archive:   %s\n", todo.ArchiveName(store.Path()))
+			fmt.Printf("Data dir:  %s\n", dir)
+			if path, err := config.Path(); err == nil {
+				fmt.Printf("Config:    %s\n", path)
+			}
+		},
+	}
+
+	// --- TRASH COMMANDS ---
+	var trashCmd = &cobra.Command{
+		Use:   "trash",
+		Short: "List, restore or purge deleted tasks",
+	}
+
+	var trashListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the tasks in the trash, most recently deleted first",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			trash := todoList.Trash()
+			if len(trash) == 0 {
+				fmt.Println("The trash is empty.")
+				return
+			}
+			for _, t := range trash {
+				fmt.Printf("%s  %s  %s\n", t.ID, t.DeletedAt.Format("2006-01-02 15:04"), t.Task)
+			}
+			if cfg.TrashDays > 0 {
+				fmt.Printf("\nTasks are deleted for good %d day(s) after they were trashed.\n", cfg.TrashDays)
+			}
+		},
+	}
+
+	var trashRestoreCmd = &cobra.Command{
+		Use:   "restore [task]",
+		Short: "Take a task out of the trash",
+		Long:  "Take a task out of the trash, by ID prefix or title.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			task, _, err := resolve(todoList.Trash(), args[0])
+			if err == nil {
+				err = todoList.Restore(task.ID)
+			}
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			saveList()
+			fmt.Println("Task restored.")
+		},
+	}
+
+	var trashEmptyCmd = &cobra.Command{
+		Use:   "empty",
+		Short: "Delete every task in the trash for good",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			purged := todoList.PurgeTrash(time.Time{})
+			if len(purged) == 0 {
+				fmt.Println("The trash is already empty.")
+				return
+			}
+			saveList()
+			fmt.Printf("Deleted %d task(s) for good.\n", len(purged))
+		},
+	}
+	trashCmd.AddCommand(trashListCmd, trashRestoreCmd, trashEmptyCmd)
+
+	// --- UNDO / REDO COMMANDS ---
+	var undoCmd = &cobra.Command{
+		Use:   "undo",
+		Short: "Undo the last change, made here or in the TUI",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			stepHistory(history.Undo, "Undid")
+		},
+	}
+
+	var redoCmd = &cobra.Command{
+		Use:   "redo",
+		Short: "Redo the last undone change",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			stepHistory(history.Redo, "Redid")
+		},
+	}
+
+	// --- CONFIG COMMANDS ---
+	var configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Show or change settings in the config file",
+		// works without a data file, and must work with a broken config
+		PersistentPreRun:  func(cmd *cobra.Command, args []string) {},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {},
+	}
+
+	var configGetCmd = &cobra.Command{
+		Use:   "get [key]",
+		Short: "Print one setting, or all of them",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			path, err := config.Path()
+			if err == nil {
+				cfg, err = config.Load(path)
+			}
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			keys := config.Keys()
+			if len(args) == 1 {
+				keys = args
+			}
+			for _, key := range keys {
+				value, err := cfg.Get(key)
+				if err != nil {
+					fmt.Println("Error:", err)
+					return
+				}
+				if len(args) == 1 {
+					fmt.Println(value)
+				} else {
+					fmt.Printf("%s = %q\n", key, value)
+				}
+			}
+		},
+	}
+
+	var configSetCmd = &cobra.Command{
+		Use:   "set [key] [value]",
+		Short: "Change a setting",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			path, err := config.Path()
+			if err == nil {
+				cfg, err = config.Load(path)
+			}
+			if err != nil {
+				fmt.Println("Error:", err)
+				fmt.Println("Fix the file with: weektcli config edit")
+				return
+			}
+
+			if err := cfg.Set(args[0], args[1]); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if err := cfg.Validate(); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if err := cfg.Save(path); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			fmt.Printf("%s = %q\n", args[0], args[1])
+		},
+	}
+
+	var configEditCmd = &cobra.Command{
+		Use:   "edit",
+		Short: "Open the config file in $EDITOR",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			path, err := config.Path()
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+				if err := config.Default().Save(path); err != nil {
+					fmt.Println("Error:", err)
+					return
+				}
+			}
+
+			editor := os.Getenv("VISUAL")
+			if editor == "" {
+				editor = os.Getenv("EDITOR")
+			}
+			if editor == "" {
+				editor = "vi"
+				if runtime.GOOS == "windows" {
+					editor = "notepad"
+				}
+			}
+
+			// the editor setting may carry flags, e.g. "code --wait"
+			fields := strings.Fields(editor)
+			c := exec.Command(fields[0], append(fields[1:], path)...)
+			c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+			if err := c.Run(); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			edited, err := config.Load(path)
+			if err == nil {
+				_, err = keyMap(edited)
+			}
+			if err != nil {
+				fmt.Println("Error:", err)
+				fmt.Println("The file was saved, but weektcli won't start until this is fixed.")
+				return
+			}
+			fmt.Println("Config saved:", path)
+		},
+	}
+	configCmd.AddCommand(configGetCmd, configSetCmd, configEditCmd)
 
 	// --- TUI COMMANDS ---
 	var tuiCmd = &cobra.Command{
 		Use:   "tui",
 		Short: "Open Weekly View",
 		Run: func(cmd *cobra.Command, args []string) {
-			p := tea.NewProgram(tui.InitialModel(&todoList), tea.WithAltScreen())
+			rollover, err := todo.ParseRolloverMode(env.RolloverMode)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			if err := applyTheme(); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			keys, err := keyMap(cfg)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			opts := tui.Options{
+				WeekStart:    cfg.WeekStartDay(),
+				DefaultView:  cfg.DefaultView,
+				ColumnWidth:  cfg.ColumnWidth,
+				ColumnHeight: cfg.ColumnHeight,
+				DayFormat:    cfg.DayFormat,
+				DateFormat:   cfg.DateFormat,
+				Rollover:     rollover,
+				Keys:         keys,
+			}
+
+			p := tea.NewProgram(tui.InitialModel(&todoList, store, opts), tea.WithAltScreen())
+			// the TUI saves after every change itself
 			p.Run()
-			todoList.Save(env.TodoFileName)
 		},
 	}
 
-	rootCmd.AddCommand(addCmd, deleteCmd, toggleCmd, editCmd, getCmd, tuiCmd)
+	// --- WEEK COMMAND ---
+	var weekOffset int
+	var weekPlain, weekColor, weekWatch bool
+	var weekCmd = &cobra.Command{
+		Use:   "week",
+		Short: "Print the week grid without opening the TUI",
+		Long: "Print the week the way the TUI shows it, for tmux panes, scripts and notes.\n" +
+			"Output that is not a terminal is plain ASCII without colors unless --color is given.",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{"load": "window"},
+		Run: func(cmd *cobra.Command, args []string) {
+			if weekPlain && weekColor {
+				fmt.Println("Error: --plain and --color exclude each other")
+				return
+			}
+			if err := applyTheme(); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+
+			tty := term.IsTerminal(os.Stdout.Fd())
+			ascii := weekPlain || (!tty && !weekColor)
+			switch {
+			case ascii:
+				lipgloss.SetColorProfile(termenv.Ascii)
+			case weekColor && !tty:
+				lipgloss.SetColorProfile(termenv.ANSI256)
+			}
+
+			opts := tui.Options{
+				WeekStart:    cfg.WeekStartDay(),
+				ColumnWidth:  cfg.ColumnWidth,
+				ColumnHeight: cfg.ColumnHeight,
+				DayFormat:    cfg.DayFormat,
+				DateFormat:   cfg.DateFormat,
+			}
+			draw := func() string {
+				width := 80
+				if w, _, err := term.GetSize(os.Stdout.Fd()); err == nil && w > 0 {
+					width = w
+				}
+				return tui.RenderWeek(&todoList, opts, weekOffset, width, ascii)
+			}
+			// the week to show, which moves on at midnight while watching
+			window := func() (time.Time, time.Time) {
+				from := todo.StartOfWeek(time.Now(), cfg.WeekStartDay()).AddDate(0, 0, 7*weekOffset)
+				return from, from.AddDate(0, 0, 6)
+			}
+			loadWindow(window())
+			if !weekWatch {
+				fmt.Println(draw())
+				return
+			}
+
+			// Redraw on every change to the data file, and each minute so
+			// the today marker moves on at midnight
+			watcher := todo.Watch(store.Path())
+			defer watcher.Close()
+			interrupt := make(chan os.Signal, 1)
+			signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+			ticker := time.NewTicker(time.Minute)
+			defer ticker.Stop()
+
+			for {
+				if tty {
+					fmt.Print("\x1b[H\x1b[2J")
+				}
+				fmt.Println(draw())
+
+				select {
+				case <-watcher.Changes():
+				case <-ticker.C:
+				case <-interrupt:
+					return
+				}
+
+				from, to := window()
+				list, err := store.Window(from, to.AddDate(0, 0, 1))
+				if err != nil && !errors.Is(err, todo.ErrRestoredBackup) {
+					fmt.Fprintln(os.Stderr, "Error: reloading tasks:", err)
+					continue
+				}
+				todoList, baseList = list, list.Snapshot()
+			}
+		},
+	}
+	weekCmd.Flags().IntVarP(&weekOffset, "offset", "o", 0, "Week relative to this one, e.g. 1 for next week")
+	weekCmd.Flags().BoolVar(&weekPlain, "plain", false, "Plain ASCII without colors, also on a terminal")
+	weekCmd.Flags().BoolVar(&weekColor, "color", false, "Colors and box drawing also when not printing to a terminal")
+	weekCmd.Flags().BoolVarP(&weekWatch, "watch", "w", false, "Keep running and redraw when the tasks change")
+
+	rootCmd.AddCommand(addCmd, deleteCmd, toggleCmd, editCmd, moveCmd, getCmd, listCmd, logCmd, agendaCmd, weekCmd, repeatCmd, rolloverCmd, trashCmd, undoCmd, redoCmd, migrateCmd, whereCmd, configCmd, tuiCmd)
 	rootCmd.Execute()
-}
\ No newline at end of file
+}
+
+// listRow is one line of `weektcli list`, also what --template sees.
+type listRow struct {
+	Index     int    `json:"index"`
+	ID        string `json:"id"`
+	Day       string `json:"day,omitempty"`      // YYYY-MM-DD, empty for Someday
+	Original  string `json:"original,omitempty"` // the day a moved occurrence was scheduled on
+	Task      string `json:"task"`
+	Notes     string `json:"notes,omitempty"`
+	Done      bool   `json:"done"`
+	Someday   bool   `json:"someday"`
+	Recurring bool   `json:"recurring"`
+	Repeats   string `json:"repeats,omitempty"`
+}
+
+// agenda is what `weektcli agenda --json` prints.
+type agenda struct {
+	Date     string    `json:"date"`
+	Overdue  []listRow `json:"overdue"`
+	Today    []listRow `json:"today"`
+	Upcoming []listRow `json:"upcoming"`
+	Counts   struct {
+		Overdue   int `json:"overdue"`
+		Today     int `json:"today"`
+		TodayDone int `json:"today_done"`
+		Upcoming  int `json:"upcoming"`
+	} `json:"counts"`
+}
+
+// newListRow describes one occurrence, numbered index.
+func newListRow(occ todo.Occurrence, index int) listRow {
+	row := listRow{
+		Index:     index,
+		ID:        occ.ID.String(),
+		Task:      occ.Task,
+		Notes:     occ.Notes,
+		Done:      occ.Done,
+		Someday:   occ.IsSomeday,
+		Recurring: occ.RecurrenceRule != nil,
+	}
+	if !occ.Day.IsZero() {
+		row.Day = occ.Day.Format(todo.DateLayout)
+	}
+	if !occ.Original.Equal(occ.Day) {
+		row.Original = occ.Original.Format(todo.DateLayout)
+	}
+	if row.Recurring {
+		row.Repeats = occ.RecurrenceRule.Describe()
+	}
+	return row
+}
+
+// saveListing remembers the numbers of the rows just printed, so other
+// commands take them as task references.
+func saveListing(rows []listRow) {
+	listing := make([]todo.Listed, len(rows))
+	for i, r := range rows {
+		listing[i] = todo.Listed{ID: uuid.MustParse(r.ID), Day: r.Day, Original: r.Original}
+	}
+	if err := todo.SaveListing(listingFile, listing); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: numbering the tasks:", err)
+	}
+}
+
+// printRows writes the rows as an aligned table, JSON, CSV or with a Go
+// template executed once per row.
+func printRows(rows []listRow, format, tmpl string) error {
+	switch format {
+	case "table":
+		if len(rows) == 0 {
+			fmt.Println("No tasks.")
+			return nil
+		}
+		short := todo.ShortIDs(todoList)
+		if windowed {
+			// the tasks left out may share a short prefix, 8 characters don't
+			for id, prefix := range short {
+				short[id] = id.String()[:max(len(prefix), 8)]
+			}
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "#\tDAY\tDONE\tTASK\tID")
+		for _, r := range rows {
+			day, done, task := "someday", "", r.Task
+			if r.Day != "" {
+				d, _ := time.ParseInLocation(todo.DateLayout, r.Day, time.Local)
+				day = d.Format("Mon 2006-01-02")
+			}
+			if r.Done {
+				done = "✔"
+			}
+			if r.Recurring {
+				task += " ↻"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", r.Index, day, done, task, short[uuid.MustParse(r.ID)])
+		}
+		return w.Flush()
+
+	case "json":
+		if rows == nil {
+			rows = []listRow{}
+		}
+		data, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"index", "id", "day", "task", "notes", "done", "someday", "recurring", "repeats"})
+		for _, r := range rows {
+			w.Write([]string{
+				strconv.Itoa(r.Index), r.ID, r.Day, r.Task, r.Notes,
+				strconv.FormatBool(r.Done), strconv.FormatBool(r.Someday), strconv.FormatBool(r.Recurring), r.Repeats,
+			})
+		}
+		w.Flush()
+		return w.Error()
+
+	case "template":
+		t, err := template.New("list").Parse(tmpl)
+		if err != nil {
+			return err
+		}
+		for _, r := range rows {
+			if err := t.Execute(os.Stdout, r); err != nil {
+				return err
+			}
+			fmt.Println()
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown output %q, expected table, json or csv", format)
+}
+
+// taskRefHelp explains the ways commands accept to name a task.
+const taskRefHelp = "The task is its ID, the shortest unique start of the ID (at least 4\n" +
+	"characters), its number in the last weektcli list, or a part of its title."
+
+// resolve finds the task ref names among items for a command that changes
+// it, see todo.Resolve. When the title matches several tasks, or only
+// loosely, and stdin is a terminal it asks which one.
+func resolve(items []todo.Item, ref string) (todo.Item, time.Time, error) {
+	return resolveRef(items, ref, false)
+}
+
+// lookup is resolve for commands that only show a task, where a loose
+// title match is good enough.
+func lookup(items []todo.Item, ref string) (todo.Item, time.Time, error) {
+	return resolveRef(items, ref, true)
+}
+
+func resolveRef(items []todo.Item, ref string, loose bool) (todo.Item, time.Time, error) {
+	listing, err := todo.LoadListing(listingFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Warning:", err)
+	}
+	task, day, err := todo.Resolve(items, ref, listing, loose)
+
+	var ambiguous *todo.AmbiguousError
+	if !errors.As(err, &ambiguous) {
+		return task, day, err
+	}
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return task, day, err
+	}
+
+	if ambiguous.Loose {
+		fmt.Printf("No title contains %q, did you mean:\n", ref)
+	} else {
+		fmt.Printf("%q matches %d tasks:\n", ref, len(ambiguous.Matches))
+	}
+	for i, it := range ambiguous.Matches {
+		when := "someday"
+		if !it.IsSomeday {
+			when = it.Date.Format("Mon 2006-01-02")
+		}
+		fmt.Printf("  %d) %s  (%s, %s)\n", i+1, it.Task, when, it.ID.String()[:8])
+	}
+	fmt.Printf("Which one? [1-%d] ", len(ambiguous.Matches))
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	n, convErr := strconv.Atoi(strings.TrimSpace(answer))
+	if convErr != nil || n < 1 || n > len(ambiguous.Matches) {
+		return todo.Item{}, time.Time{}, errors.New("no task picked")
+	}
+	return ambiguous.Matches[n-1], time.Time{}, nil
+}
+
+// parseDay reads a date flag, see todo.ParseDate. Someday is no day and
+// only makes sense where a task can be sent there.
+func parseDay(s string) (time.Time, error) {
+	day, someday, err := todo.ParseDate(s, time.Now(), cfg.WeekStartDay())
+	if err == nil && someday {
+		err = errors.New("someday is not a day, use a date")
+	}
+	return day, err
+}
+
+// originalOn finds the occurrence of task shown on day, and returns the
+// day it was originally scheduled on, which the todo package addresses
+// occurrences by. A day without an occurrence is returned as it is.
+func originalOn(task todo.Item, day time.Time) (time.Time, error) {
+	occs := todo.List{task}.OccurrencesOn(day)
+	switch {
+	case task.RecurrenceRule == nil || len(occs) == 0:
+		return day, nil
+	case len(occs) > 1:
+		return day, fmt.Errorf("%q shows up %d times on %s, pick one by its number in weektcli list", task.Task, len(occs), day.Format(todo.DateLayout))
+	}
+	return occs[0].Original, nil
+}
+
+// applyTheme loads the theme from the config and styles the TUI with it.
+func applyTheme() error {
+	themesDir, _ := config.ThemesDir()
+	theme, err := tui.LoadTheme(cfg.Theme, themesDir)
+	if err != nil {
+		return err
+	}
+	tui.ApplyTheme(theme)
+	return nil
+}
+
+// keyMap is the default key map with the [keys] tables of c applied.
+func keyMap(c config.Config) (tui.KeyMap, error) {
+	keys := tui.DefaultKeyMap()
+	if err := keys.Remap(c.Keys); err != nil {
+		return keys, fmt.Errorf("config: %w", err)
+	}
+	return keys, nil
+}
+
+// saveList writes the list back to disk and records the change for undo.
+// A failed save is fatal for the CLI so scripts don't report success for
+// changes that were never stored.
+func saveList() {
+	before := baseList
+	writeList()
+	if err := history.Record(before, todoList); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: undo history:", err)
+	}
+}
+
+// writeList is saveList without the undo history, for housekeeping the
+// user didn't ask for and shouldn't undo by accident.
+func writeList() {
+	if err := todo.SaveChanges(store, baseList, todoList); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: saving tasks:", err)
+		os.Exit(1)
+	}
+	baseList = todoList.Snapshot()
+}
+
+// purgeExpiredTrash deletes tasks that have been in the trash for longer
+// than the trash_days setting for good.
+func purgeExpiredTrash() {
+	if cfg.TrashDays == 0 {
+		return
+	}
+	if purged := todoList.PurgeTrash(time.Now().AddDate(0, 0, -cfg.TrashDays)); len(purged) > 0 {
+		writeList()
+	}
+}
+
+// archiveCompleted moves tasks done more than archive_weeks ago to the
+// archive store. The list is only saved once the archive has them.
+func archiveCompleted() {
+	if cfg.ArchiveWeeks == 0 {
+		return
+	}
+	next := todoList.Snapshot()
+	archived := next.Archive(time.Now().AddDate(0, 0, -7*cfg.ArchiveWeeks))
+	if len(archived) == 0 {
+		return
+	}
+
+	archive, err := todo.OpenArchive(store)
+	if err == nil {
+		err = archive.Upsert(archived...)
+		archive.Close()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: archiving done tasks:", err)
+		return
+	}
+	todoList = next
+	writeList()
+	fmt.Fprintf(os.Stderr, "Archived %d completion(s) from more than %d week(s) ago, see weektcli log\n", len(archived), cfg.ArchiveWeeks)
+}
+
+// stepHistory runs an undo or redo and saves the result, which is not
+// recorded as a change of its own.
+func stepHistory(step func(*todo.List, func() error) (todo.Command, error), done string) {
+	c, err := step(&todoList, func() error {
+		return todo.SaveChanges(store, baseList, todoList)
+	})
+	if errors.Is(err, todo.ErrNothingToUndo) || errors.Is(err, todo.ErrNothingToRedo) {
+		fmt.Println(strings.ToUpper(err.Error()[:1]) + err.Error()[1:] + ".")
+		return
+	}
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	baseList = todoList.Snapshot()
+	fmt.Printf("%s: %s\n", done, c)
+}
+
+// openStore opens the storage backend. Without an explicit file the data
+// lives in env.DataDir.
+func openStore(kind, file string) {
+	sqlite := strings.EqualFold(kind, "sqlite")
+
+	path, jsonPath := file, ""
+	if path != "" {
+		path, _ = filepath.Abs(path)
+	} else {
+		dir, err := env.DataDir()
+		if err == nil {
+			err = os.MkdirAll(dir, 0755)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: data directory:", err)
+			os.Exit(1)
+		}
+
+		jsonPath = filepath.Join(dir, env.TodoFileName)
+		adoptLegacyFile(jsonPath)
+
+		path = jsonPath
+		if sqlite {
+			path = filepath.Join(dir, env.TodoDBName)
+		}
+	}
+	_, statErr := os.Stat(path)
+
+	var err error
+	if store, err = todo.OpenStore(kind, path); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	history = todo.NewHistory(todo.HistoryName(path))
+	listingFile = todo.ListingName(path)
+
+	// A new database starts out with the tasks from the JSON file
+	if sqlite && jsonPath != "" && errors.Is(statErr, os.ErrNotExist) {
+		var existing todo.List
+		if existing.Load(jsonPath) == nil && len(existing) > 0 {
+			if err := store.Save(existing); err != nil {
+				fmt.Fprintln(os.Stderr, "Error: importing", jsonPath+":", err)
+				os.Exit(1)
+			}
+			fmt.Fprintf(os.Stderr, "Imported %d task(s) from %s into %s\n", len(existing), jsonPath, path)
+		}
+	}
+
+}
+
+// setList makes the list a store returned the one commands work on.
+func setList(list todo.List, err error) {
+	if err != nil {
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			// first run, start with an empty list
+		case errors.Is(err, todo.ErrRestoredBackup):
+			fmt.Fprintln(os.Stderr, "Warning:", err)
+		default:
+			// Refuse to run: saving now would overwrite whatever is left
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+	}
+	todoList, baseList = list, list.Snapshot()
+}
+
+// loadWindow loads only the tasks that can show up from from to to, both
+// days included, and the Someday tasks. It is for commands that don't
+// change tasks, so a big SQLite store isn't read in full to print a week.
+func loadWindow(from, to time.Time) {
+	windowed = true
+	setList(store.Window(from, to.AddDate(0, 0, 1)))
+}
+
+// adoptLegacyFile moves a data file left in the working directory by older
+// versions, which saved next to wherever weektcli was run, to target. It
+// only does so once: when target does not exist yet.
+func adoptLegacyFile(target string) {
+	legacy, err := filepath.Abs(env.TodoFileName)
+	if err != nil || legacy == target {
+		return
+	}
+	if _, err := os.Stat(target); !errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		return
+	}
+
+	if err := moveFile(legacy, target); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: could not move", legacy, "to", target+":", err)
+		return
+	}
+	// the backup is only worth keeping together with its file
+	moveFile(todo.BackupName(legacy), todo.BackupName(target))
+	os.Remove(todo.LockName(legacy))
+	fmt.Fprintf(os.Stderr, "Moved %s to %s\n", legacy, target)
+}
+
+// moveFile renames src to dst, copying when they are on different devices.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(dst, data, 0644); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// loadConfig reads the config file into cfg. A broken config stops the
+// program rather than silently running with defaults.
+func loadConfig() {
+	path, err := config.Path()
+	if err == nil {
+		cfg, err = config.Load(path)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: config:", err)
+		fmt.Fprintln(os.Stderr, "Fix it with: weektcli config edit")
+		os.Exit(1)
+	}
+}